@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/oaxacos/vitacare/internal/bootstrap"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+	"github.com/oaxacos/vitacare/pkg/logger"
+)
+
+// app holds the dependencies shared by the server and the operational
+// commands, so both build the services the same way.
+type app struct {
+	*bootstrap.App
+	db       *db.DBRepository
+	migrator *db.Migrator
+}
+
+func newApp(conf *config.Config) (*app, error) {
+	dbRepo, err := db.NewConnection(conf)
+	if err != nil {
+		return nil, err
+	}
+	logger.GetGlobalLogger().Info("connected to database")
+
+	migrator, err := db.NewMigrator(dbRepo)
+	if err != nil {
+		_ = dbRepo.Close()
+		return nil, err
+	}
+
+	return &app{
+		App:      bootstrap.New(conf, bootstrap.Storage{DB: dbRepo}),
+		db:       dbRepo,
+		migrator: migrator,
+	}, nil
+}
+
+func (a *app) Close() {
+	logs := logger.GetGlobalLogger()
+	logs.Info("closing connection")
+	if err := a.db.Close(); err != nil {
+		logs.Error(err)
+	}
+}
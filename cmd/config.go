@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/server"
+)
+
+var errConfigUsage = errors.New("usage: vitacare config print")
+
+// printConfig writes the effective config, after the defaults, environment
+// and secret files are applied, without the secrets.
+func printConfig(conf *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errConfigUsage
+	}
+	content, err := conf.Redacted().YAML()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(content)
+	return err
+}
+
+// reloadOnSignal reads the config again on SIGHUP and applies the log level
+// and the cors origins, the other changes need a restart.
+func reloadOnSignal(ctx context.Context, conf *config.Config, s *server.Server) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	logs := logger.GetGlobalLogger()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		next, err := config.NewConfig("")
+		if err != nil {
+			logs.Errorf("config not reloaded: %s", err)
+			continue
+		}
+		if err := logger.SetLevel(logger.Level(next)); err != nil {
+			logs.Errorf("log level not reloaded: %s", err)
+		}
+		s.Reload(next)
+		if changed := conf.RestartRequired(next); len(changed) > 0 {
+			logs.Warnf("config reloaded, the changes to %v need a restart", changed)
+			continue
+		}
+		logs.Info("config reloaded")
+	}
+}
@@ -1,61 +1,72 @@
 package main
 
 import (
-	_ "github.com/oaxacos/vitacare/docs"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/oaxacos/vitacare/docs/v0"
+	_ "github.com/oaxacos/vitacare/docs/v1"
 	"github.com/oaxacos/vitacare/internal/config"
-	"github.com/oaxacos/vitacare/internal/domain/repository/password"
-	tokenRepository "github.com/oaxacos/vitacare/internal/domain/repository/token"
-	userRepository "github.com/oaxacos/vitacare/internal/domain/repository/user"
-	"github.com/oaxacos/vitacare/internal/domain/service/token"
-	"github.com/oaxacos/vitacare/internal/domain/service/user"
-	"github.com/oaxacos/vitacare/internal/infrastructure/db"
-	"github.com/oaxacos/vitacare/internal/infrastructure/http"
 	"github.com/oaxacos/vitacare/pkg/logger"
-	"github.com/oaxacos/vitacare/pkg/server"
-	"github.com/oaxacos/vitacare/pkg/validator"
 )
 
-// @title VitaCare API
-// @version 0.0
-// @description This the service of Vitacare.
-//@securityDefinitions.apikey Token
-//@in header
-//@name Authorization
+type command struct {
+	name        string
+	description string
+	run         func(conf *config.Config, args []string) error
+}
+
+var commands = []command{
+	{"serve", "start the api, the default command", serve},
+	{"migrate", "up|down|status|create, manage the database schema", migrate},
+	{"create-admin", "create an admin user", createAdmin},
+	{"reset-password", "set a new password for a user and revoke the sessions", resetPassword},
+	{"revoke-sessions", "log a user out of every device", revokeSessions},
+	{"purge-expired-tokens", "delete the expired refresh tokens", purgeExpiredTokens},
+	{"seed", "create demo users for development", seed},
+	{"config", "print, show the effective config without the secrets", printConfig},
+}
+
+func usage() string {
+	var b strings.Builder
+	b.WriteString("usage: vitacare <command> [flags]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "  %-22s %s\n", cmd.name, cmd.description)
+	}
+	return b.String()
+}
 
 func main() {
 	logs := logger.GetGlobalLogger()
-	defer logger.CloseLogger()
 
 	conf, err := config.NewConfig("")
 	if err != nil {
 		logs.Fatal(err)
 	}
+	logs = logger.Init(conf)
 
-	dbRepo, err := db.NewConnection(conf)
-	if err != nil {
-		logs.Fatal(err)
+	name, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		name, args = os.Args[1], os.Args[2:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Print(usage())
+		return
 	}
 
-	logs.Info("connected to database")
-
-	defer func() {
-		logs.Info("closing connection")
-		dbRepo.Close()
-	}()
-	passRepo := password.NewPasswordRepository(dbRepo)
-	userRepo := userRepository.NewUserRepository(dbRepo)
-	tokenRepo := tokenRepository.NewTokenRepository(dbRepo)
-	validation := validator.New()
-
-	userSvc := user.NewUserService(userRepo, passRepo)
-	tokenSvc := token.NewTokenService(conf, tokenRepo)
-
-	s := server.NewServer(conf)
-
-	http.NewUserController(s, userSvc, tokenSvc, validation)
+	err = fmt.Errorf("unknown command %q\n%s", name, usage())
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err = cmd.run(conf, args)
+			break
+		}
+	}
 
-	err = s.Start()
 	if err != nil {
-		logs.Fatal(err)
+		logs.Error(err)
+		logger.CloseLogger()
+		os.Exit(1)
 	}
+	logger.CloseLogger()
 }
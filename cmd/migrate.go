@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+	"github.com/oaxacos/vitacare/migrations"
+	"github.com/oaxacos/vitacare/pkg/logger"
+)
+
+const migrateUsage = "usage: vitacare migrate up|down|status|create [-dir migrations] <name>"
+
+var errMigrateUsage = errors.New(migrateUsage)
+
+func migrate(conf *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+	subcommand, args := args[0], args[1:]
+
+	// create only writes a file, it doesn't need the database
+	if subcommand == "create" {
+		flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+		dir := flags.String("dir", "migrations", "migrations directory")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errMigrateUsage
+		}
+		file, err := migrations.Create(*dir, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", file)
+		return nil
+	}
+
+	dbRepo, err := db.NewConnection(conf)
+	if err != nil {
+		return err
+	}
+	defer dbRepo.Close()
+
+	migrator, err := db.NewMigrator(dbRepo)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	logs := logger.GetGlobalLogger()
+	switch subcommand {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logs.Infof("applied %d migrations", len(applied))
+	case "down":
+		version, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		logs.Infof("rolled back migration %s", version)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, migration := range status {
+			state := "pending"
+			if migration.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", migration.Version, migration.Name, state)
+		}
+		return w.Flush()
+	default:
+		return errMigrateUsage
+	}
+	return nil
+}
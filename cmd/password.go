@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/pkg/validator"
+	"golang.org/x/term"
+)
+
+// passwordEnv lets scripts provide the password, flags would leak it in the
+// process list.
+const passwordEnv = "VITACARE_PASSWORD"
+
+var errPasswordMismatch = errors.New("passwords don't match")
+
+// readPassword takes the password from the environment, asks for it twice on
+// a terminal or reads a line from stdin.
+func readPassword() (string, error) {
+	if password, ok := os.LookupEnv(passwordEnv); ok {
+		return password, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "confirm password: ")
+	confirmation, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(password) != string(confirmation) {
+		return "", errPasswordMismatch
+	}
+	return string(password), nil
+}
+
+// validatePassword applies the rule of the registration form.
+func validatePassword(password string) error {
+	data := dto.UserDto{Password: password, PasswordConfirmation: password}
+	return validator.New().ValidatePartial(context.Background(), data, "Password", "PasswordConfirmation")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/pkg/logger"
+)
+
+var errSeedNotDebug = errors.New("seed only runs with server.debug enabled, use -force to run it anyway")
+
+var seedUsers = []struct {
+	firstName string
+	lastName  string
+	email     string
+	role      model.UserRole
+}{
+	{"Admin", "Demo", "admin@vitacare.local", model.AdminRole},
+	{"Doctor", "Demo", "doctor@vitacare.local", model.DoctorRole},
+	{"Secretary", "Demo", "secretary@vitacare.local", model.SecretaryRole},
+	{"Patient", "Demo", "patient@vitacare.local", model.PatientRole},
+}
+
+// seed creates one user per role, the users that already exist are skipped
+// so it can run again.
+func seed(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	force := flags.Bool("force", false, "seed even when debug is disabled")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !conf.Server.Debug && !*force {
+		return errSeedNotDebug
+	}
+	plainText, err := readPassword()
+	if err != nil {
+		return err
+	}
+	if err := validatePassword(plainText); err != nil {
+		return err
+	}
+
+	a, err := newApp(conf)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	logs := logger.GetGlobalLogger()
+	for _, seedUser := range seedUsers {
+		err := a.Services.User.ExistUser(ctx, seedUser.email)
+		if errors.Is(err, user.ErrUserAlreadyExist) {
+			logs.Infof("%s already exists", seedUser.email)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = a.Services.User.CreateUserWithRole(ctx, dto.UserDto{
+			FirstName: seedUser.firstName,
+			LastName:  seedUser.lastName,
+			Email:     seedUser.email,
+			Password:  plainText,
+		}, seedUser.role)
+		if err != nil {
+			return err
+		}
+		logs.Infof("created %s %s", seedUser.role, seedUser.email)
+	}
+	return nil
+}
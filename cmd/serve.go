@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/metrics"
+	"github.com/oaxacos/vitacare/pkg/server"
+	"github.com/oaxacos/vitacare/pkg/tracing"
+)
+
+func serve(conf *config.Config, args []string) error {
+	logs := logger.GetGlobalLogger()
+
+	shutdownTracing, err := tracing.Init(context.Background(), conf.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logs.Error(err)
+		}
+	}()
+
+	a, err := newApp(conf)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if err := a.migrator.CheckSchema(context.Background()); err != nil {
+		return err
+	}
+
+	if conf.Server.Metrics.Enabled {
+		if err := metrics.RegisterDB(a.db.DB.DB, conf.Database.DbName); err != nil {
+			logs.Error(err)
+		}
+		if a.db.Replica != nil {
+			if err := metrics.RegisterDB(a.db.Replica.DB, conf.Database.DbName+"_replica"); err != nil {
+				logs.Error(err)
+			}
+		}
+	}
+
+	s := server.NewServer(conf)
+	s.Health.Register(a.db.PingCheck())
+	s.Health.Register(a.migrator.MigrationCheck())
+	if a.db.Replica != nil {
+		s.Health.Register(a.db.ReplicaCheck())
+	}
+
+	var rateLimitStore *db.RateLimitStore
+	if conf.RateLimit.Store == "postgres" {
+		rateLimitStore = db.NewRateLimitStore(a.db)
+		s.RateLimiter.SetStore(rateLimitStore)
+	}
+
+	a.Register(s)
+
+	// deferred calls run after the server is drained: the workers are
+	// stopped and waited for, then the database is closed and the logger
+	// flushed last
+	var workers sync.WaitGroup
+	defer workers.Wait()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+
+	runWorker(func(ctx context.Context) { a.Services.Account.RunAnonymizer(ctx, time.Hour) })
+	if a.Services.Webhook != nil {
+		runWorker(func(ctx context.Context) {
+			a.Services.Webhook.RunDispatcher(ctx, time.Duration(conf.Webhooks.Interval)*time.Second)
+		})
+	}
+	go reloadOnSignal(ctx, conf, s)
+	if rateLimitStore != nil {
+		runWorker(func(ctx context.Context) { rateLimitStore.RunPurge(ctx, time.Hour) })
+	}
+
+	if err := s.Run(ctx); err != nil {
+		return err
+	}
+	logs.Info("server stopped")
+	return nil
+}
@@ -0,0 +1,15 @@
+package main
+
+// The general info of the swagger docs of every api version, the routes are
+// shared and relative to the base path.
+
+// @title VitaCare API
+// @version 0.0
+// @description This the service of Vitacare, v0 is deprecated in favor of v1.
+// @BasePath /api/v0
+//@securityDefinitions.apikey Token
+//@in header
+//@name Authorization
+//@securityDefinitions.apikey ApiKey
+//@in header
+//@name X-API-Key
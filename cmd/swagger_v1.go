@@ -0,0 +1,12 @@
+package main
+
+// @title VitaCare API
+// @version 1.0
+// @description This the service of Vitacare.
+// @BasePath /api/v1
+//@securityDefinitions.apikey Token
+//@in header
+//@name Authorization
+//@securityDefinitions.apikey ApiKey
+//@in header
+//@name X-API-Key
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/validator"
+)
+
+var errEmailRequired = errors.New("-email is required")
+
+func createAdmin(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the admin")
+	firstName := flags.String("first-name", "Admin", "first name of the admin")
+	lastName := flags.String("last-name", "VitaCare", "last name of the admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errEmailRequired
+	}
+	plainText, err := readPassword()
+	if err != nil {
+		return err
+	}
+	data := dto.UserDto{
+		FirstName:            *firstName,
+		LastName:             *lastName,
+		Email:                *email,
+		Password:             plainText,
+		PasswordConfirmation: plainText,
+	}
+
+	if err := validator.New().ValidateStruct(context.Background(), data); err != nil {
+		return err
+	}
+
+	a, err := newApp(conf)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	if err := a.Services.User.ExistUser(ctx, data.Email); err != nil {
+		return err
+	}
+	admin, err := a.Services.User.CreateUserWithRole(ctx, data, model.AdminRole)
+	if err != nil {
+		return err
+	}
+	logger.GetGlobalLogger().Infof("admin %s created with id %s", admin.Email, admin.ID)
+	return nil
+}
+
+func resetPassword(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errEmailRequired
+	}
+	plainText, err := readPassword()
+	if err != nil {
+		return err
+	}
+	if err := validatePassword(plainText); err != nil {
+		return err
+	}
+
+	a, err := newApp(conf)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	user, err := a.Services.User.GetByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	if err := a.Services.User.ResetPassword(ctx, user.ID, plainText); err != nil {
+		return err
+	}
+	// whoever knew the old password must not keep a session
+	if err := a.revokeSessions(ctx, user); err != nil {
+		return err
+	}
+	logger.GetGlobalLogger().Infof("password of %s reset", user.Email)
+	return nil
+}
+
+func revokeSessions(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("revoke-sessions", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errEmailRequired
+	}
+
+	a, err := newApp(conf)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	user, err := a.Services.User.GetByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	if err := a.revokeSessions(ctx, user); err != nil {
+		return err
+	}
+	logger.GetGlobalLogger().Infof("sessions of %s revoked, access tokens already issued stay valid until they expire", user.Email)
+	return nil
+}
+
+func (a *app) revokeSessions(ctx context.Context, user *model.User) error {
+	if err := a.Services.Token.DeleteRefreshTokenByUser(ctx, user.ID); err != nil {
+		return err
+	}
+	a.Services.Audit.LogAs(ctx, model.AuditSessionsRevoked, uuid.Nil, user.ID, map[string]string{
+		"source": "cli",
+	})
+	return nil
+}
+
+func purgeExpiredTokens(conf *config.Config, args []string) error {
+	a, err := newApp(conf)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	deleted, err := a.Services.Token.PurgeExpiredTokens(context.Background())
+	if err != nil {
+		return err
+	}
+	logger.GetGlobalLogger().Infof("deleted %d expired refresh tokens", deleted)
+	return nil
+}
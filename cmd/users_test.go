@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	conf, err := config.NewConfig("test")
+	require.NoError(t, err)
+	return conf
+}
+
+func TestUsage(t *testing.T) {
+	text := usage()
+	for _, cmd := range commands {
+		assert.Contains(t, text, cmd.name)
+	}
+}
+
+func TestReadPasswordFromEnv(t *testing.T) {
+	t.Setenv(passwordEnv, "supersecret")
+	password, err := readPassword()
+	require.NoError(t, err)
+	assert.Equal(t, "supersecret", password)
+}
+
+func TestValidatePassword(t *testing.T) {
+	assert.NoError(t, validatePassword("supersecret"))
+
+	appErr, ok := apperror.As(validatePassword("123"))
+	require.True(t, ok, "the rule of the registration form is applied")
+	require.Len(t, appErr.Fields, 2)
+	assert.Equal(t, "min", appErr.Fields[0].Tag)
+}
+
+// TestCommandsCheckTheirInput covers the errors returned before the commands
+// connect to the database.
+func TestCommandsCheckTheirInput(t *testing.T) {
+	conf := testConfig(t)
+
+	for _, run := range []func(*config.Config, []string) error{createAdmin, resetPassword, revokeSessions} {
+		assert.ErrorIs(t, run(conf, nil), errEmailRequired)
+	}
+
+	t.Setenv(passwordEnv, "123")
+	_, ok := apperror.As(createAdmin(conf, []string{"-email", "admin@test.com"}))
+	assert.True(t, ok, "a short password is rejected")
+	_, ok = apperror.As(resetPassword(conf, []string{"-email", "admin@test.com"}))
+	assert.True(t, ok, "a short password is rejected")
+
+	conf.Server.Debug = false
+	assert.ErrorIs(t, seed(conf, nil), errSeedNotDebug)
+}
+
+// TestCommandsWithDatabase runs against the test database, it is skipped
+// when the database is not reachable.
+func TestCommandsWithDatabase(t *testing.T) {
+	conf := testConfig(t)
+	repoDb, err := db.NewConnection(conf)
+	if err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+	_ = repoDb.Close()
+
+	email := uuid.NewString() + "@test.com"
+	t.Setenv(passwordEnv, "supersecret")
+	require.NoError(t, createAdmin(conf, []string{"-email", email}))
+	assert.ErrorIs(t, createAdmin(conf, []string{"-email", email}), user.ErrUserAlreadyExist)
+
+	assert.ErrorIs(t, resetPassword(conf, []string{"-email", "nobody-" + email}), user.ErrEmailNotFound)
+	assert.ErrorIs(t, revokeSessions(conf, []string{"-email", "nobody-" + email}), user.ErrEmailNotFound)
+
+	t.Setenv(passwordEnv, "anothersecret")
+	require.NoError(t, resetPassword(conf, []string{"-email", email}))
+	require.NoError(t, revokeSessions(conf, []string{"-email", email}))
+
+	a, err := newApp(conf)
+	require.NoError(t, err)
+	defer a.Close()
+	_, err = a.Services.User.LoginUser(context.Background(), dto.UserLoginDto{Email: email, Password: "anothersecret"})
+	assert.NoError(t, err, "the new password is set")
+}
@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserExportDto struct {
+	ExportedAt   time.Time          `json:"exported_at"`
+	User         UserProfileDto     `json:"user"`
+	Addresses    []AddressDto       `json:"addresses"`
+	Insurance    []InsuranceDto     `json:"insurance"`
+	Appointments []AppointmentDto   `json:"appointments"`
+	Sessions     []SessionExportDto `json:"sessions"`
+}
+
+type UserProfileDto struct {
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	FirstName           string     `json:"first_name"`
+	LastName            string     `json:"last_name"`
+	Role                string     `json:"role"`
+	Dni                 string     `json:"dni"`
+	Phone               string     `json:"phone"`
+	Language            string     `json:"language"`
+	BirthDate           *time.Time `json:"birth_date"`
+	IsActive            bool       `json:"is_active"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at"`
+}
+
+type AddressDto struct {
+	ID           uuid.UUID `json:"id"`
+	AddressLine1 string    `json:"address_line_1"`
+	AddressLine2 string    `json:"address_line_2"`
+	ZipCode      string    `json:"zip_code"`
+	City         string    `json:"city"`
+	State        string    `json:"state"`
+	Country      string    `json:"country"`
+}
+
+type InsuranceDto struct {
+	ID                   uuid.UUID `json:"id"`
+	Name                 string    `json:"name"`
+	Institution          string    `json:"institution"`
+	SocialSecurityNumber string    `json:"social_security_number"`
+}
+
+type AppointmentDto struct {
+	ID        uuid.UUID  `json:"id"`
+	Date      time.Time  `json:"date"`
+	DoctorID  uuid.UUID  `json:"doctor_id"`
+	ServiceID *uuid.UUID `json:"service_id"`
+	PackageID *uuid.UUID `json:"package_id"`
+	Subtotal  float64    `json:"subtotal"`
+	Total     float64    `json:"total"`
+	PaymentAt *time.Time `json:"payment_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type SessionExportDto struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type AccountDeletionDto struct {
+	Message      string    `json:"message"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
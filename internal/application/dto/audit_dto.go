@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditEventDto struct {
+	ID        uuid.UUID         `json:"id"`
+	Seq       int64             `json:"seq"`
+	Action    string            `json:"action"`
+	ActorID   *uuid.UUID        `json:"actor_id"`
+	TargetID  *uuid.UUID        `json:"target_id"`
+	IP        string            `json:"ip"`
+	RequestID string            `json:"request_id"`
+	Metadata  map[string]string `json:"metadata"`
+	CreatedAt time.Time         `json:"created_at"`
+	Hash      string            `json:"hash"`
+}
+
+type AuditEventListDto struct {
+	Events []AuditEventDto `json:"events"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+type AuditVerificationDto struct {
+	Valid    bool  `json:"valid"`
+	Checked  int   `json:"checked"`
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+type MarkDeceasedDto struct {
+	DeceasedAt string `json:"deceased_at" validate:"required,datetime=2006-01-02"` // YYYY-MM-DD
+}
+
+type MarkDeceasedResponse struct {
+	Message               string `json:"message"`
+	CancelledAppointments int    `json:"cancelled_appointments"`
+}
+
+type PatientDto struct {
+	ID        uuid.UUID `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+}
+
+type PatientListDto struct {
+	Patients []PatientDto `json:"patients"`
+	Total    int          `json:"total"`
+	Limit    int          `json:"limit"`
+	Offset   int          `json:"offset"`
+}
@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateServiceAccountDto struct {
+	Name        string `json:"name" validate:"required,min=3,max=64"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+type ServiceAccountDto struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ServiceAccountListDto struct {
+	ServiceAccounts []ServiceAccountDto `json:"service_accounts"`
+}
+
+type CreateAPIKeyDto struct {
+	Name string `json:"name" validate:"required,min=3,max=64"`
+	// patients:read, patients:write or audit:read
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+	// RateLimit in requests per minute, 0 uses the limit of the routes
+	RateLimit int `json:"rate_limit" validate:"gte=0"`
+	// ExpiresAt is a RFC3339 date, the key doesn't expire without it
+	ExpiresAt string `json:"expires_at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type APIKeyDto struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// CreatedAPIKeyDto is the only response that holds the key, it can't be
+// recovered later.
+type CreatedAPIKeyDto struct {
+	APIKeyDto
+	Key string `json:"key"`
+}
+
+type APIKeyListDto struct {
+	Keys []APIKeyDto `json:"keys"`
+}
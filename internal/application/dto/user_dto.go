@@ -10,6 +10,8 @@ type UserDto struct {
 	Email                string `json:"email" validate:"required,email"`
 	Password             string `json:"password" validate:"required,min=6"`
 	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password,min=6"`
+	// Language defaults to the Accept-Language of the request
+	Language string `json:"language" validate:"omitempty,locale" enums:"es,en"`
 }
 
 type UserLoggedInDto struct {
@@ -28,6 +30,7 @@ type User struct {
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
 	Email     string    `json:"email"`
+	Language  string    `json:"language"`
 }
 
 type UpdateUserRoleDto struct {
@@ -37,7 +40,8 @@ type UpdateUserRoleDto struct {
 type UpdateUserDto struct {
 	FirstName string `json:"first_name" validate:"omitempty,min=3"`
 	LastName  string `json:"last_name" validate:"omitempty,min=3"`
-	Dni       string `json:"dni" validate:"omitempty,min=3"`
-	Phone     string `json:"phone" validate:"omitempty,min=3"`
+	Dni       string `json:"dni" validate:"omitempty,dni"` // CURP or clave de elector
+	Phone     string `json:"phone" validate:"omitempty,phone"`
 	BirthDate string `json:"birth_date" validate:"omitempty,min=3"` // YYYY-MM-DD
+	Language  string `json:"language" validate:"omitempty,locale" enums:"es,en"`
 }
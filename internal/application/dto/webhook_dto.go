@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateWebhookDto struct {
+	URL         string `json:"url" validate:"required,url"`
+	Description string `json:"description" validate:"max=255"`
+	// patient.registered, user.role_changed or appointment.cancelled
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+}
+
+type WebhookDto struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      []string  `json:"events"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreatedWebhookDto is the only response that holds the secret the
+// deliveries are signed with.
+type CreatedWebhookDto struct {
+	WebhookDto
+	Secret string `json:"secret"`
+}
+
+type WebhookListDto struct {
+	Webhooks []WebhookDto `json:"webhooks"`
+}
+
+type WebhookDeliveryDto struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	ResponseStatus int             `json:"response_status"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+type WebhookDeliveryListDto struct {
+	Deliveries []WebhookDeliveryDto `json:"deliveries"`
+	Total      int                  `json:"total"`
+	Limit      int                  `json:"limit"`
+	Offset     int                  `json:"offset"`
+}
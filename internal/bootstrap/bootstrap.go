@@ -0,0 +1,249 @@
+// Package bootstrap builds the repositories, the services and the
+// controllers of the api from the config, module by module. The server and
+// the operational commands use it so every dependency is wired in one place.
+package bootstrap
+
+import (
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+	addressRepository "github.com/oaxacos/vitacare/internal/domain/repository/address"
+	appointmentRepository "github.com/oaxacos/vitacare/internal/domain/repository/appointment"
+	auditRepository "github.com/oaxacos/vitacare/internal/domain/repository/audit"
+	insuranceRepository "github.com/oaxacos/vitacare/internal/domain/repository/insurance"
+	memoryRepository "github.com/oaxacos/vitacare/internal/domain/repository/memory"
+	"github.com/oaxacos/vitacare/internal/domain/repository/password"
+	serviceAccountRepository "github.com/oaxacos/vitacare/internal/domain/repository/serviceaccount"
+	tokenRepository "github.com/oaxacos/vitacare/internal/domain/repository/token"
+	userRepository "github.com/oaxacos/vitacare/internal/domain/repository/user"
+	webhookRepository "github.com/oaxacos/vitacare/internal/domain/repository/webhook"
+	"github.com/oaxacos/vitacare/internal/domain/service/account"
+	"github.com/oaxacos/vitacare/internal/domain/service/apikey"
+	"github.com/oaxacos/vitacare/internal/domain/service/audit"
+	"github.com/oaxacos/vitacare/internal/domain/service/token"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/internal/domain/service/webhook"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+	"github.com/oaxacos/vitacare/internal/infrastructure/http"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/server"
+	"github.com/oaxacos/vitacare/pkg/validator"
+)
+
+type Repositories struct {
+	User        repository.UserRepository
+	Password    repository.PasswordRepository
+	Token       repository.RefreshTokenRepository
+	Address     repository.AddressRepository
+	Insurance   repository.InsuranceRepository
+	Appointment repository.AppointmentRepository
+	Audit       repository.AuditRepository
+	Account     repository.ServiceAccountRepository
+	APIKey      repository.APIKeyRepository
+	Webhook     repository.WebhookSubscriptionRepository
+	Delivery    repository.WebhookDeliveryRepository
+}
+
+// Storage is where the repositories keep their data, the postgres database
+// of the api or, when Store is set, the memory store of the tests.
+type Storage struct {
+	DB    *db.DBRepository
+	Store *memoryRepository.Store
+}
+
+// Services are nil for the modules that are not enabled.
+type Services struct {
+	Audit   *audit.AuditService
+	User    *user.UserService
+	Token   *token.TokenSvc
+	Account *account.AccountService
+	APIKey  *apikey.APIKeyService
+	Webhook *webhook.WebhookService
+}
+
+// Module is a feature of the api, it builds its own repositories and
+// services and the controllers that serve them.
+type Module struct {
+	Name string
+	// Enabled gates the module on its config section, a module without it
+	// is always enabled
+	Enabled func(conf *config.Config) bool
+	// Repositories adds the repositories of the module on storage to repos
+	Repositories func(storage Storage, repos *Repositories)
+	// Services adds the services of the module, they can use the services
+	// of the modules before it
+	Services    func(conf *config.Config, repos Repositories, services *Services)
+	Controllers func(deps http.Dependencies) []server.Controller
+}
+
+// Modules are built and registered in order, a new feature only has to be
+// added here. The audit log is used by every module and the users by the
+// authentication of the routes, so they go first.
+var Modules = []Module{
+	{
+		Name: "audit",
+		Repositories: func(storage Storage, repos *Repositories) {
+			if storage.Store != nil {
+				repos.Audit = memoryRepository.NewAuditRepository(storage.Store)
+				return
+			}
+			repos.Audit = auditRepository.NewAuditRepository(storage.DB)
+		},
+		Services: func(conf *config.Config, repos Repositories, services *Services) {
+			services.Audit = audit.NewAuditService(repos.Audit)
+		},
+		Controllers: func(deps http.Dependencies) []server.Controller {
+			return []server.Controller{http.NewAuditController(deps)}
+		},
+	},
+	{
+		Name: "webhooks",
+		Enabled: func(conf *config.Config) bool {
+			return conf.Webhooks.Enabled
+		},
+		Repositories: func(storage Storage, repos *Repositories) {
+			if storage.Store != nil {
+				repos.Webhook = memoryRepository.NewWebhookSubscriptionRepository(storage.Store)
+				repos.Delivery = memoryRepository.NewWebhookDeliveryRepository(storage.Store)
+				return
+			}
+			repos.Webhook = webhookRepository.NewSubscriptionRepository(storage.DB)
+			repos.Delivery = webhookRepository.NewDeliveryRepository(storage.DB)
+		},
+		Services: func(conf *config.Config, repos Repositories, services *Services) {
+			services.Webhook = webhook.NewWebhookService(conf, repos.Webhook, repos.Delivery, services.Audit)
+		},
+		Controllers: func(deps http.Dependencies) []server.Controller {
+			return []server.Controller{http.NewWebhookController(deps)}
+		},
+	},
+	{
+		Name: "users",
+		Repositories: func(storage Storage, repos *Repositories) {
+			if storage.Store != nil {
+				repos.User = memoryRepository.NewUserRepository(storage.Store)
+				repos.Password = memoryRepository.NewPasswordRepository(storage.Store)
+				repos.Token = memoryRepository.NewTokenRepository(storage.Store)
+				repos.Address = memoryRepository.NewAddressRepository(storage.Store)
+				repos.Insurance = memoryRepository.NewInsuranceRepository(storage.Store)
+				repos.Appointment = memoryRepository.NewAppointmentRepository(storage.Store)
+				return
+			}
+			repos.User = userRepository.NewUserRepository(storage.DB)
+			repos.Password = password.NewPasswordRepository(storage.DB)
+			repos.Token = tokenRepository.NewTokenRepository(storage.DB)
+			repos.Address = addressRepository.NewAddressRepository(storage.DB)
+			repos.Insurance = insuranceRepository.NewInsuranceRepository(storage.DB)
+			repos.Appointment = appointmentRepository.NewAppointmentRepository(storage.DB)
+		},
+		Services: func(conf *config.Config, repos Repositories, services *Services) {
+			var publisher webhook.Publisher = webhook.NopPublisher{}
+			if services.Webhook != nil {
+				publisher = services.Webhook
+			}
+			services.User = user.NewUserService(repos.User, repos.Password, services.Audit, publisher)
+			services.Token = token.NewTokenService(conf, repos.Token)
+			services.Account = account.NewAccountService(conf, account.Repositories{
+				User:        repos.User,
+				Password:    repos.Password,
+				Token:       repos.Token,
+				Address:     repos.Address,
+				Insurance:   repos.Insurance,
+				Appointment: repos.Appointment,
+			}, services.Audit, publisher)
+		},
+		Controllers: func(deps http.Dependencies) []server.Controller {
+			return []server.Controller{http.NewUserController(deps), http.NewAccountController(deps)}
+		},
+	},
+	{
+		Name: "service-accounts",
+		Repositories: func(storage Storage, repos *Repositories) {
+			if storage.Store != nil {
+				repos.Account = memoryRepository.NewServiceAccountRepository(storage.Store)
+				repos.APIKey = memoryRepository.NewAPIKeyRepository(storage.Store)
+				return
+			}
+			repos.Account = serviceAccountRepository.NewServiceAccountRepository(storage.DB)
+			repos.APIKey = serviceAccountRepository.NewAPIKeyRepository(storage.DB)
+		},
+		Services: func(conf *config.Config, repos Repositories, services *Services) {
+			services.APIKey = apikey.NewAPIKeyService(repos.Account, repos.APIKey, services.Audit)
+		},
+		Controllers: func(deps http.Dependencies) []server.Controller {
+			return []server.Controller{http.NewServiceAccountController(deps)}
+		},
+	},
+	{
+		Name: "patients",
+		Controllers: func(deps http.Dependencies) []server.Controller {
+			return []server.Controller{http.NewPatientController(deps)}
+		},
+	},
+}
+
+// EnabledModules are the modules conf enables, in order.
+func EnabledModules(conf *config.Config) []Module {
+	enabled := make([]Module, 0, len(Modules))
+	for _, module := range Modules {
+		if module.Enabled == nil || module.Enabled(conf) {
+			enabled = append(enabled, module)
+		}
+	}
+	return enabled
+}
+
+// App holds the dependencies shared by the server and the operational
+// commands.
+type App struct {
+	Config       *config.Config
+	Repositories Repositories
+	Services     Services
+	Validator    *validator.Validator
+	modules      []Module
+}
+
+// New builds the repositories and the services of the modules conf enables.
+func New(conf *config.Config, storage Storage) *App {
+	app := &App{
+		Config:    conf,
+		Validator: validator.New(),
+		modules:   EnabledModules(conf),
+	}
+	for _, module := range app.modules {
+		if module.Repositories != nil {
+			module.Repositories(storage, &app.Repositories)
+		}
+		if module.Services != nil {
+			module.Services(conf, app.Repositories, &app.Services)
+		}
+	}
+	return app
+}
+
+// Dependencies are the ones the controllers of s are built with.
+func (a *App) Dependencies(s *server.Server) http.Dependencies {
+	return http.Dependencies{
+		Config:    a.Config,
+		Validator: a.Validator,
+		Cookies:   s.Cookies,
+		RateLimit: s.RateLimit,
+		Users:     a.Services.User,
+		Tokens:    a.Services.Token,
+		Accounts:  a.Services.Account,
+		Audit:     a.Services.Audit,
+		APIKeys:   a.Services.APIKey,
+		Webhooks:  a.Services.Webhook,
+	}
+}
+
+// Register mounts the controllers of the enabled modules on s.
+func (a *App) Register(s *server.Server) {
+	deps := a.Dependencies(s)
+	for _, module := range a.modules {
+		if module.Controllers == nil {
+			continue
+		}
+		s.Register(module.Controllers(deps)...)
+		logger.GetGlobalLogger().Debugf("module %s registered", module.Name)
+	}
+}
@@ -0,0 +1,46 @@
+// Package bootstrapTest spins up the whole api with in-memory repositories,
+// so the controllers can be tested end to end without a database.
+package bootstrapTest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oaxacos/vitacare/internal/bootstrap"
+	"github.com/oaxacos/vitacare/internal/config"
+	memoryRepository "github.com/oaxacos/vitacare/internal/domain/repository/memory"
+	"github.com/oaxacos/vitacare/pkg/server"
+)
+
+type Server struct {
+	*server.Server
+	App *bootstrap.App
+	// Store holds the data of the repositories, use it to seed the tests
+	Store *memoryRepository.Store
+}
+
+// NewServer registers every module on a new server, conf defaults to the
+// test config.
+func NewServer(t testing.TB, conf *config.Config) *Server {
+	t.Helper()
+	if conf == nil {
+		var err error
+		conf, err = config.NewConfig("test")
+		if err != nil {
+			t.Fatalf("error loading config %v", err)
+		}
+	}
+	store := memoryRepository.NewStore()
+	app := bootstrap.New(conf, bootstrap.Storage{Store: store})
+	s := server.NewServer(conf)
+	app.Register(s)
+	return &Server{Server: s, App: app, Store: store}
+}
+
+// Do serves req and returns the recorded response.
+func (s *Server) Do(req *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	return recorder
+}
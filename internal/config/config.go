@@ -7,6 +7,7 @@ import (
 	"github.com/knadh/koanf/parsers/yaml"
 	env2 "github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/providers/structs"
 	"github.com/knadh/koanf/v2"
 	"strings"
 )
@@ -15,10 +16,78 @@ type Server struct {
 	Port   int  `koanf:"port"`
 	Debug  bool `koanf:"debug"`
 	Pretty bool `koanf:"pretty"`
+	// LogFormat is console or json
+	LogFormat string `koanf:"log-format"`
+	// LogLevel is debug, info, warn or error, empty uses debug when Debug is
+	// set, it can be changed without a restart
+	LogLevel string  `koanf:"log-level"`
+	Metrics  Metrics `koanf:"metrics"`
+	// timeouts in seconds, 0 uses the default
+	ReadTimeout       int `koanf:"read-timeout"`
+	ReadHeaderTimeout int `koanf:"read-header-timeout"`
+	WriteTimeout      int `koanf:"write-timeout"`
+	IdleTimeout       int `koanf:"idle-timeout"`
+	ShutdownTimeout   int `koanf:"shutdown-timeout"`
+	// DrainDelay is the wait between failing the readiness probe and closing
+	// the listener, so the load balancer stops sending requests first
+	DrainDelay int `koanf:"drain-delay"`
+	// RequestTimeout cancels the context of the requests that take longer, 0 disables it
+	RequestTimeout int `koanf:"request-timeout"`
+	// MaxBodySize limits the json request bodies, in KB, the routes can set
+	// their own limit
+	MaxBodySize int    `koanf:"max-body-size"`
+	Health      Health `koanf:"health"`
+	// Versions marks the api versions as deprecated, by name like v0
+	Versions map[string]APIVersion `koanf:"versions"`
+}
+
+type APIVersion struct {
+	// Deprecated is the date, YYYY-MM-DD, the version was deprecated
+	Deprecated string `koanf:"deprecated"`
+	// Sunset is the date the version will be removed, it can be empty
+	Sunset string `koanf:"sunset"`
+	// Link documents the migration to the next version
+	Link string `koanf:"link"`
+}
+
+type Health struct {
+	// CheckTimeout bounds every readiness check, in seconds
+	CheckTimeout int `koanf:"check-timeout"`
+	// CacheTTL is how long a check result is reused, in seconds
+	CacheTTL int `koanf:"cache-ttl"`
+}
+
+type Metrics struct {
+	Enabled bool `koanf:"enabled"`
+	// Port serves /metrics on a separate listener when it is not 0
+	Port int `koanf:"port"`
+	// Token protects /metrics with a bearer token when it is not empty
+	Token string `koanf:"token"`
 }
 
 type Cors struct {
-	TrustedOrigins []string `koanf:"trusted-origins"`
+	// TrustedOrigins are the origins allowed to call the api, like
+	// https://example.com, https://*.example.com for its subdomains or * for
+	// any origin
+	TrustedOrigins   []string `koanf:"trusted-origins"`
+	AllowedMethods   []string `koanf:"allowed-methods"`
+	AllowedHeaders   []string `koanf:"allowed-headers"`
+	ExposedHeaders   []string `koanf:"exposed-headers"`
+	AllowCredentials bool     `koanf:"allow-credentials"`
+	// MaxAge is how long the browsers cache a preflight, in seconds
+	MaxAge int `koanf:"max-age"`
+	// Routes override the settings above for some paths
+	Routes []CorsRoute `koanf:"routes"`
+}
+
+// CorsRoute applies to the requests to Path and the paths under it, the
+// longest match wins. The empty settings are inherited.
+type CorsRoute struct {
+	Path             string   `koanf:"path"`
+	TrustedOrigins   []string `koanf:"trusted-origins"`
+	AllowedMethods   []string `koanf:"allowed-methods"`
+	AllowedHeaders   []string `koanf:"allowed-headers"`
+	AllowCredentials *bool    `koanf:"allow-credentials"`
 }
 
 type Database struct {
@@ -27,6 +96,29 @@ type Database struct {
 	Port     int    `koanf:"port"`
 	Password string `koanf:"password"`
 	Username string `koanf:"username"`
+	// SSLMode is disable, allow, prefer, require, verify-ca or verify-full
+	SSLMode string `koanf:"sslmode"`
+	// SSLRootCert is the path of the CA certificate that signed the server one
+	SSLRootCert string `koanf:"sslrootcert"`
+	// pool limits, the lifetimes are in seconds and 0 means no limit
+	MaxOpenConns    int `koanf:"max-open-conns"`
+	MaxIdleConns    int `koanf:"max-idle-conns"`
+	ConnMaxLifetime int `koanf:"conn-max-lifetime"`
+	ConnMaxIdleTime int `koanf:"conn-max-idle-time"`
+	// StatementTimeout cancels the queries running longer, in seconds, 0 disables it
+	StatementTimeout int `koanf:"statement-timeout"`
+	// ConnectRetries is how many times the first connection is retried with backoff
+	ConnectRetries int     `koanf:"connect-retries"`
+	Replica        Replica `koanf:"replica"`
+}
+
+// Replica is a read only copy of the database, the list and report queries
+// use it when Host is set. The credentials default to the primary ones.
+type Replica struct {
+	Host     string `koanf:"host"`
+	Port     int    `koanf:"port"`
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
 }
 
 var (
@@ -35,15 +127,23 @@ var (
 )
 
 type Config struct {
-	Server   Server   `koanf:"server"`
-	Database Database `koanf:"database"`
-	Cors     Cors     `koanf:"cors"`
-	Token    Token    `koanf:"token"`
+	Server    Server    `koanf:"server"`
+	Database  Database  `koanf:"database"`
+	Cors      Cors      `koanf:"cors"`
+	Token     Token     `koanf:"token"`
+	Privacy   Privacy   `koanf:"privacy"`
+	Tracing   Tracing   `koanf:"tracing"`
+	RateLimit RateLimit `koanf:"ratelimit"`
+	Security  Security  `koanf:"security"`
+	Webhooks  Webhooks  `koanf:"webhooks"`
 }
 
 func getConfigFile(env []string) (*koanf.Koanf, error) {
 
 	k := koanf.New(".")
+	if err := k.Load(structs.Provider(defaultConfig(), "koanf"), nil); err != nil {
+		return nil, err
+	}
 	confile := "config/config.yaml"
 	if len(env) > 0 && env[0] != "" {
 		confile = fmt.Sprintf("config/config.%s.yaml", env[0])
@@ -61,11 +161,88 @@ func getConfigFile(env []string) (*koanf.Koanf, error) {
 
 type Token struct {
 	PrivateKeyAccessToken  string `koanf:"access-token-key"`
-	PrivateKeyRefreshToken string `koan:"refresh-token-key"`
+	PrivateKeyRefreshToken string `koanf:"refresh-token-key"`
 	AccessTimeExpiration   int    `koanf:"access-time-expiration"`
 	RefreshTimeExpiration  int    `koanf:"refresh-time-expiration"`
 }
 
+type Tracing struct {
+	Enabled bool `koanf:"enabled"`
+	// Exporter is stdout or otlp
+	Exporter    string  `koanf:"exporter"`
+	Endpoint    string  `koanf:"endpoint"`
+	Insecure    bool    `koanf:"insecure"`
+	ServiceName string  `koanf:"service-name"`
+	SampleRatio float64 `koanf:"sample-ratio"`
+}
+
+type RateLimit struct {
+	Enabled bool `koanf:"enabled"`
+	// Store is memory or postgres, the memory store counts the requests of
+	// every instance on its own
+	Store string `koanf:"store"`
+	// Groups are the limits of the route groups by name, a group without
+	// limit is not limited
+	Groups map[string]RateLimitGroup `koanf:"groups"`
+}
+
+type RateLimitGroup struct {
+	Requests int `koanf:"requests"`
+	// Window in seconds
+	Window int `koanf:"window"`
+	// Key is ip, user or api-key
+	Key string `koanf:"key"`
+}
+
+type Security struct {
+	// HSTSMaxAge is the max-age of Strict-Transport-Security in seconds, 0
+	// does not send it
+	HSTSMaxAge int `koanf:"hsts-max-age"`
+	// ContentSecurityPolicy is not sent by the swagger ui, it loads scripts
+	ContentSecurityPolicy string `koanf:"content-security-policy"`
+	// FrameOptions is DENY or SAMEORIGIN
+	FrameOptions   string `koanf:"frame-options"`
+	ReferrerPolicy string `koanf:"referrer-policy"`
+	// CSRF requires the double submit token on the routes authenticated by
+	// the refresh token cookie
+	CSRF   bool   `koanf:"csrf"`
+	Cookie Cookie `koanf:"cookie"`
+}
+
+// Cookie are the attributes of the refresh token and csrf cookies.
+type Cookie struct {
+	Domain string `koanf:"domain"`
+	Path   string `koanf:"path"`
+	// SameSite is strict, lax or none, none requires Secure
+	SameSite string `koanf:"same-site"`
+	Secure   bool   `koanf:"secure"`
+}
+
+type Webhooks struct {
+	Enabled bool `koanf:"enabled"`
+	// AllowHTTP accepts subscriptions without tls, for development
+	AllowHTTP bool `koanf:"allow-http"`
+	// AllowPrivateNetworks lets the deliveries reach loopback, link-local and
+	// private addresses, for development
+	AllowPrivateNetworks bool `koanf:"allow-private-networks"`
+	// Interval in seconds between the polls of the due deliveries
+	Interval  int `koanf:"interval"`
+	BatchSize int `koanf:"batch-size"`
+	// Timeout in seconds of every attempt
+	Timeout     int `koanf:"timeout"`
+	MaxAttempts int `koanf:"max-attempts"`
+	// BackoffBase and BackoffMax in seconds, the wait doubles after every
+	// failed attempt
+	BackoffBase int `koanf:"backoff-base"`
+	BackoffMax  int `koanf:"backoff-max"`
+}
+
+type Privacy struct {
+	// DeletionGracePeriod is the number of days an account deletion request
+	// can be cancelled before the user data is anonymized.
+	DeletionGracePeriod int `koanf:"deletion-grace-period"`
+}
+
 var errConfigEmpty = errors.New("config file is empty")
 
 func NewConfig(env ...string) (*Config, error) {
@@ -75,18 +252,131 @@ func NewConfig(env ...string) (*Config, error) {
 	}
 
 	// load from env, we can override the config file
+	keys := envKeys(k)
 	err = k.Load(env2.Provider("", ".", func(s string) string {
-		val := strings.ToLower(strings.Replace(s, "_", ".", -1))
-		return val
+		// the secret files are read by loadSecretFiles
+		if strings.HasSuffix(s, secretFileEnvSuffix) {
+			return ""
+		}
+		return envKey(keys, s)
 	}), nil)
 	if err != nil {
 		return nil, err
 	}
+	if err := loadSecretFiles(k, keys); err != nil {
+		return nil, err
+	}
 	var conf *Config
 	err = k.Unmarshal("", &conf)
 	if err != nil {
 		return nil, err
 	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
 
 	return conf, nil
 }
+
+// envKeys maps the environment name of every known key to the key, the _ of
+// a name stands for a . or a -, TOKEN_ACCESS_TOKEN_KEY is
+// token.access-token-key.
+func envKeys(k *koanf.Koanf) map[string]string {
+	keys := make(map[string]string)
+	for _, key := range k.Keys() {
+		keys[strings.ToUpper(envSeparators.Replace(key))] = key
+	}
+	return keys
+}
+
+var envSeparators = strings.NewReplacer(".", "_", "-", "_")
+
+// envKey returns the key of the environment variable s, the names that match
+// no known key, like the entries of a map missing in the config file, only
+// have dots.
+func envKey(keys map[string]string, s string) string {
+	if key, ok := keys[strings.ToUpper(s)]; ok {
+		return key
+	}
+	return strings.ToLower(strings.Replace(s, "_", ".", -1))
+}
+
+func defaultConfig() Config {
+	return Config{
+		Server: Server{
+			Port:        8000,
+			LogFormat:   "console",
+			MaxBodySize: 1024,
+			DrainDelay:  5,
+			Health: Health{
+				CheckTimeout: 2,
+				CacheTTL:     5,
+			},
+		},
+		Database: Database{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 1800,
+			ConnMaxIdleTime: 300,
+			ConnectRetries:  5,
+			Replica: Replica{
+				Port: 5432,
+			},
+		},
+		Cors: Cors{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Accept-Language", "X-Request-ID", "X-API-Key", "X-CSRF-Token"},
+			ExposedHeaders: []string{
+				"X-Request-ID", "X-CSRF-Token", "Content-Language", "Retry-After",
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+			},
+			AllowCredentials: true,
+			MaxAge:           600,
+		},
+		Token: Token{
+			AccessTimeExpiration:  15,
+			RefreshTimeExpiration: 24,
+		},
+		Privacy: Privacy{
+			DeletionGracePeriod: 30,
+		},
+		Tracing: Tracing{
+			Exporter:    "stdout",
+			ServiceName: "vitacare",
+			SampleRatio: 1,
+		},
+		Security: Security{
+			HSTSMaxAge:            31536000,
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+			CSRF:                  true,
+			Cookie: Cookie{
+				Path:     "/",
+				SameSite: "none",
+				Secure:   true,
+			},
+		},
+		Webhooks: Webhooks{
+			Enabled:     true,
+			Interval:    10,
+			BatchSize:   50,
+			Timeout:     10,
+			MaxAttempts: 8,
+			BackoffBase: 30,
+			BackoffMax:  6 * 3600,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Store:   "memory",
+			Groups: map[string]RateLimitGroup{
+				"auth":     {Requests: 10, Window: 60, Key: "ip"},
+				"api-auth": {Requests: 600, Window: 60, Key: "ip"},
+				"api":      {Requests: 300, Window: 60, Key: "user"},
+			},
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfig(t *testing.T) {
+	t.Run("loads the refresh token key", func(t *testing.T) {
+		conf, err := NewConfig("test")
+		assert.NoError(t, err)
+		assert.Equal(t, "refresh-token-key", conf.Token.PrivateKeyRefreshToken)
+		assert.Equal(t, "console", conf.Server.LogFormat)
+	})
+
+	t.Run("reads secrets from files", func(t *testing.T) {
+		secret := filepath.Join(t.TempDir(), "db-password")
+		assert.NoError(t, os.WriteFile(secret, []byte("from-file\n"), 0o600))
+		t.Setenv("DATABASE_PASSWORD_FILE", secret)
+
+		conf, err := NewConfig("test")
+		assert.NoError(t, err)
+		assert.Equal(t, "from-file", conf.Database.Password)
+	})
+
+	t.Run("reads the hyphenated keys", func(t *testing.T) {
+		secret := filepath.Join(t.TempDir(), "access-token-key")
+		assert.NoError(t, os.WriteFile(secret, []byte("access-token-key-from-file\n"), 0o600))
+		t.Setenv("TOKEN_ACCESS_TOKEN_KEY_FILE", secret)
+		t.Setenv("DATABASE_MAX_OPEN_CONNS", "42")
+
+		conf, err := NewConfig("test")
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token-key-from-file", conf.Token.PrivateKeyAccessToken)
+		assert.Equal(t, 42, conf.Database.MaxOpenConns)
+	})
+
+	t.Run("fails when a secret file matches no key", func(t *testing.T) {
+		secret := filepath.Join(t.TempDir(), "secret")
+		assert.NoError(t, os.WriteFile(secret, []byte("secret"), 0o600))
+		t.Setenv("TOKEN_ACCES_KEY_FILE", secret)
+		_, err := NewConfig("test")
+		assert.ErrorContains(t, err, "TOKEN_ACCES_KEY_FILE")
+	})
+
+	t.Run("fails when a secret file is missing", func(t *testing.T) {
+		t.Setenv("DATABASE_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+		_, err := NewConfig("test")
+		assert.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	conf := defaultConfig()
+	conf.Cors.TrustedOrigins = []string{"localhost:3000", "*"}
+	conf.Cors.Routes = []CorsRoute{{Path: "public", TrustedOrigins: []string{"https://*.example.com"}}}
+	conf.Tracing.SampleRatio = 2
+	conf.Security.Cookie.Secure = false
+	conf.Server.Versions = map[string]APIVersion{"v0": {Deprecated: "2025-04-01", Sunset: "2025-01-01"}}
+	conf.RateLimit.Groups = map[string]RateLimitGroup{"auth": {Requests: 0, Window: 60, Key: "token"}}
+
+	err := conf.Validate()
+	assert.ErrorContains(t, err, "database.dbname: is required")
+	assert.ErrorContains(t, err, "token.access-token-key: must have at least 16 characters")
+	assert.ErrorContains(t, err, "token.refresh-token-key: must have at least 16 characters")
+	assert.ErrorContains(t, err, `cors.trusted-origins: "localhost:3000"`)
+	assert.ErrorContains(t, err, "cors.trusted-origins: * can not be used with allow-credentials")
+	assert.ErrorContains(t, err, `cors.routes.0.path: "public" must start with /`)
+	assert.NotContains(t, err.Error(), "cors.routes.0.trusted-origins")
+	assert.ErrorContains(t, err, "tracing.sample-ratio")
+	assert.ErrorContains(t, err, "server.versions.v0.sunset: can not be before the deprecation")
+	assert.ErrorContains(t, err, "security.cookie.secure: is required when same-site is none")
+	assert.ErrorContains(t, err, "ratelimit.groups.auth.requests: must be a positive number")
+	assert.ErrorContains(t, err, "ratelimit.groups.auth.key: must be ip, user or api-key")
+
+	conf = defaultConfig()
+	conf.Database.DbName = "vitacare"
+	conf.Database.Username = "postgres"
+	conf.Token.PrivateKeyAccessToken = "0123456789abcdef"
+	conf.Token.PrivateKeyRefreshToken = "fedcba9876543210"
+	assert.NoError(t, conf.Validate())
+}
+
+func TestRedacted(t *testing.T) {
+	conf := defaultConfig()
+	conf.Database.Password = "hunter2"
+	conf.Token.PrivateKeyAccessToken = "secret"
+
+	redactedConf := conf.Redacted()
+	assert.Equal(t, redacted, redactedConf.Database.Password)
+	assert.Equal(t, redacted, redactedConf.Token.PrivateKeyAccessToken)
+	assert.Empty(t, redactedConf.Token.PrivateKeyRefreshToken)
+	assert.Equal(t, "hunter2", conf.Database.Password)
+
+	content, err := redactedConf.YAML()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "hunter2")
+}
+
+func TestRestartRequired(t *testing.T) {
+	current := defaultConfig()
+	next := defaultConfig()
+	next.Server.LogLevel = "debug"
+	next.Cors.TrustedOrigins = []string{"https://vitacare.example"}
+	next.RateLimit.Groups = map[string]RateLimitGroup{"auth": {Requests: 5, Window: 60, Key: "ip"}}
+	assert.Empty(t, current.RestartRequired(&next))
+
+	next.Database.Host = "db.internal"
+	assert.Equal(t, []string{"database"}, current.RestartRequired(&next))
+
+	next.RateLimit.Store = "postgres"
+	assert.Equal(t, []string{"database", "ratelimit"}, current.RestartRequired(&next))
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/structs"
+	"github.com/knadh/koanf/v2"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the config without the secrets, safe to print.
+func (c *Config) Redacted() *Config {
+	clone := *c
+	clone.Cors.TrustedOrigins = append([]string(nil), c.Cors.TrustedOrigins...)
+	redact := func(value *string) {
+		if *value != "" {
+			*value = redacted
+		}
+	}
+	redact(&clone.Database.Password)
+	redact(&clone.Database.Replica.Password)
+	redact(&clone.Token.PrivateKeyAccessToken)
+	redact(&clone.Token.PrivateKeyRefreshToken)
+	redact(&clone.Server.Metrics.Token)
+	return &clone
+}
+
+// YAML encodes the config with the same keys as the config file.
+func (c *Config) YAML() ([]byte, error) {
+	k := koanf.New(".")
+	if err := k.Load(structs.Provider(c, "koanf"), nil); err != nil {
+		return nil, err
+	}
+	return k.Marshal(yaml.Parser())
+}
+
+// RestartRequired lists the sections that changed in next and can not be
+// applied on a reload, only the log level, the cors origins and the rate
+// limits can.
+func (c *Config) RestartRequired(next *Config) []string {
+	current, updated := *c, *next
+	current.Server.LogLevel, updated.Server.LogLevel = "", ""
+	current.Cors, updated.Cors = Cors{}, Cors{}
+	current.RateLimit = RateLimit{Store: current.RateLimit.Store}
+	updated.RateLimit = RateLimit{Store: updated.RateLimit.Store}
+
+	var changed []string
+	sections := []struct {
+		name           string
+		current, value any
+	}{
+		{"server", current.Server, updated.Server},
+		{"database", current.Database, updated.Database},
+		{"token", current.Token, updated.Token},
+		{"privacy", current.Privacy, updated.Privacy},
+		{"tracing", current.Tracing, updated.Tracing},
+		{"ratelimit", current.RateLimit, updated.RateLimit},
+		{"security", current.Security, updated.Security},
+		{"webhooks", current.Webhooks, updated.Webhooks},
+	}
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.current, section.value) {
+			changed = append(changed, section.name)
+		}
+	}
+	return changed
+}
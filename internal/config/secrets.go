@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/knadh/koanf/v2"
+)
+
+const (
+	// secretFileEnvSuffix follows the docker convention, DATABASE_PASSWORD_FILE
+	// holds the path of the file with the value of database.password
+	secretFileEnvSuffix = "_FILE"
+	// secretFileKeySuffix is the same for the config file, password-file
+	// holds the path of the file with the value of password
+	secretFileKeySuffix = "-file"
+)
+
+// sections limits the _FILE variables read to the ones of this config, other
+// programs use the same suffix.
+var sections = map[string]bool{
+	"server":    true,
+	"database":  true,
+	"cors":      true,
+	"token":     true,
+	"privacy":   true,
+	"tracing":   true,
+	"ratelimit": true,
+	"security":  true,
+	"webhooks":  true,
+}
+
+// loadSecretFiles replaces the secrets given as a path with the content of
+// the file, like the ones mounted by docker and kubernetes. The environment
+// wins over the config file, a _FILE variable of a section that matches no
+// key of keys is an error.
+func loadSecretFiles(k *koanf.Koanf, keys map[string]string) error {
+	for _, key := range k.Keys() {
+		if !strings.HasSuffix(key, secretFileKeySuffix) {
+			continue
+		}
+		path := k.String(key)
+		if path == "" {
+			continue
+		}
+		if err := setFromFile(k, strings.TrimSuffix(key, secretFileKeySuffix), path); err != nil {
+			return err
+		}
+	}
+
+	for _, env := range os.Environ() {
+		name, path, _ := strings.Cut(env, "=")
+		if !strings.HasSuffix(name, secretFileEnvSuffix) || path == "" {
+			continue
+		}
+		name = strings.TrimSuffix(name, secretFileEnvSuffix)
+		section, field, _ := strings.Cut(strings.ToLower(name), "_")
+		if !sections[section] || field == "" {
+			continue
+		}
+		key, ok := keys[strings.ToUpper(name)]
+		if !ok {
+			return fmt.Errorf("%s%s does not match a config key", name, secretFileEnvSuffix)
+		}
+		if err := setFromFile(k, key, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setFromFile(k *koanf.Koanf, key, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading secret file of %s: %w", key, err)
+	}
+	return k.Set(key, strings.TrimSpace(string(content)))
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const minKeyLength = 16
+
+var (
+	logFormats = []string{"", "console", "json"}
+	logLevels  = []string{"", "debug", "info", "warn", "error"}
+	exporters  = []string{"stdout", "otlp"}
+	sslModes   = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	rateStores = []string{"memory", "postgres"}
+	rateKeys   = []string{"ip", "user", "api-key"}
+	frameOpts  = []string{"", "DENY", "SAMEORIGIN"}
+	sameSites  = []string{"strict", "lax", "none"}
+)
+
+// Validate checks the whole config and reports every invalid setting at
+// once, so a bad deploy fails on startup with a clear message.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if !validPort(c.Server.Port) {
+		invalid("server.port", "%d is not a valid port", c.Server.Port)
+	}
+	if !oneOf(c.Server.LogFormat, logFormats) {
+		invalid("server.log-format", "must be console or json")
+	}
+	if !oneOf(c.Server.LogLevel, logLevels) {
+		invalid("server.log-level", "must be debug, info, warn or error")
+	}
+	timeouts := []struct {
+		key   string
+		value int
+	}{
+		{"server.read-timeout", c.Server.ReadTimeout},
+		{"server.read-header-timeout", c.Server.ReadHeaderTimeout},
+		{"server.write-timeout", c.Server.WriteTimeout},
+		{"server.idle-timeout", c.Server.IdleTimeout},
+		{"server.shutdown-timeout", c.Server.ShutdownTimeout},
+		{"server.drain-delay", c.Server.DrainDelay},
+		{"server.request-timeout", c.Server.RequestTimeout},
+		{"server.health.check-timeout", c.Server.Health.CheckTimeout},
+		{"server.health.cache-ttl", c.Server.Health.CacheTTL},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			invalid(timeout.key, "can not be negative")
+		}
+	}
+	if c.Server.MaxBodySize < 0 {
+		invalid("server.max-body-size", "can not be negative")
+	}
+	if c.Server.Metrics.Port != 0 {
+		if !validPort(c.Server.Metrics.Port) {
+			invalid("server.metrics.port", "%d is not a valid port", c.Server.Metrics.Port)
+		} else if c.Server.Metrics.Port == c.Server.Port {
+			invalid("server.metrics.port", "must be different from server.port")
+		}
+	}
+	for name, version := range c.Server.Versions {
+		key := "server.versions." + name
+		deprecated, err := time.Parse(time.DateOnly, version.Deprecated)
+		if err != nil {
+			invalid(key+".deprecated", "must be a YYYY-MM-DD date")
+		}
+		if version.Sunset == "" {
+			continue
+		}
+		sunset, err := time.Parse(time.DateOnly, version.Sunset)
+		if err != nil {
+			invalid(key+".sunset", "must be a YYYY-MM-DD date")
+		} else if sunset.Before(deprecated) {
+			invalid(key+".sunset", "can not be before the deprecation")
+		}
+	}
+
+	if c.Database.DbName == "" {
+		invalid("database.dbname", "is required")
+	}
+	if c.Database.Host == "" {
+		invalid("database.host", "is required")
+	}
+	if c.Database.Username == "" {
+		invalid("database.username", "is required")
+	}
+	if !validPort(c.Database.Port) || c.Database.Port == 0 {
+		invalid("database.port", "%d is not a valid port", c.Database.Port)
+	}
+	if !oneOf(c.Database.SSLMode, sslModes) {
+		invalid("database.sslmode", "must be disable, allow, prefer, require, verify-ca or verify-full")
+	}
+	if c.Database.SSLRootCert != "" {
+		if _, err := os.Stat(c.Database.SSLRootCert); err != nil {
+			invalid("database.sslrootcert", "%s", err)
+		}
+	}
+	pool := []struct {
+		key   string
+		value int
+	}{
+		{"database.max-open-conns", c.Database.MaxOpenConns},
+		{"database.max-idle-conns", c.Database.MaxIdleConns},
+		{"database.conn-max-lifetime", c.Database.ConnMaxLifetime},
+		{"database.conn-max-idle-time", c.Database.ConnMaxIdleTime},
+		{"database.statement-timeout", c.Database.StatementTimeout},
+		{"database.connect-retries", c.Database.ConnectRetries},
+	}
+	for _, setting := range pool {
+		if setting.value < 0 {
+			invalid(setting.key, "can not be negative")
+		}
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		invalid("database.max-idle-conns", "can not be greater than database.max-open-conns")
+	}
+	if c.Database.Replica.Host != "" && (!validPort(c.Database.Replica.Port) || c.Database.Replica.Port == 0) {
+		invalid("database.replica.port", "%d is not a valid port", c.Database.Replica.Port)
+	}
+
+	validateOrigins := func(key string, origins []string, credentials bool) {
+		for _, origin := range origins {
+			if !validOrigin(origin) {
+				invalid(key, "%q must be a scheme and host like https://example.com or https://*.example.com", origin)
+			} else if origin == "*" && credentials {
+				invalid(key, "* can not be used with allow-credentials")
+			}
+		}
+	}
+	validateOrigins("cors.trusted-origins", c.Cors.TrustedOrigins, c.Cors.AllowCredentials)
+	if c.Cors.MaxAge < 0 {
+		invalid("cors.max-age", "can not be negative")
+	}
+	for i, route := range c.Cors.Routes {
+		key := fmt.Sprintf("cors.routes.%d", i)
+		if !strings.HasPrefix(route.Path, "/") {
+			invalid(key+".path", "%q must start with /", route.Path)
+		}
+		credentials := c.Cors.AllowCredentials
+		if route.AllowCredentials != nil {
+			credentials = *route.AllowCredentials
+		}
+		origins := route.TrustedOrigins
+		if len(origins) == 0 && route.AllowCredentials != nil {
+			origins = c.Cors.TrustedOrigins
+		}
+		validateOrigins(key+".trusted-origins", origins, credentials)
+	}
+
+	if len(c.Token.PrivateKeyAccessToken) < minKeyLength {
+		invalid("token.access-token-key", "must have at least %d characters", minKeyLength)
+	}
+	if len(c.Token.PrivateKeyRefreshToken) < minKeyLength {
+		invalid("token.refresh-token-key", "must have at least %d characters", minKeyLength)
+	}
+	if c.Token.PrivateKeyAccessToken != "" && c.Token.PrivateKeyAccessToken == c.Token.PrivateKeyRefreshToken {
+		invalid("token.refresh-token-key", "must be different from token.access-token-key")
+	}
+	if c.Token.AccessTimeExpiration <= 0 {
+		invalid("token.access-time-expiration", "must be a positive number of minutes")
+	}
+	if c.Token.RefreshTimeExpiration <= 0 {
+		invalid("token.refresh-time-expiration", "must be a positive number of hours")
+	}
+
+	if c.Privacy.DeletionGracePeriod < 0 {
+		invalid("privacy.deletion-grace-period", "can not be negative")
+	}
+
+	if c.Tracing.Enabled && !oneOf(c.Tracing.Exporter, exporters) {
+		invalid("tracing.exporter", "must be stdout or otlp")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample-ratio", "must be between 0 and 1")
+	}
+
+	if c.Security.HSTSMaxAge < 0 {
+		invalid("security.hsts-max-age", "can not be negative")
+	}
+	if !oneOf(c.Security.FrameOptions, frameOpts) {
+		invalid("security.frame-options", "must be DENY or SAMEORIGIN")
+	}
+	if !oneOf(c.Security.Cookie.SameSite, sameSites) {
+		invalid("security.cookie.same-site", "must be strict, lax or none")
+	} else if c.Security.Cookie.SameSite == "none" && !c.Security.Cookie.Secure {
+		invalid("security.cookie.secure", "is required when same-site is none")
+	}
+	if c.Security.Cookie.Path != "" && !strings.HasPrefix(c.Security.Cookie.Path, "/") {
+		invalid("security.cookie.path", "must start with /")
+	}
+
+	if !oneOf(c.RateLimit.Store, rateStores) {
+		invalid("ratelimit.store", "must be memory or postgres")
+	}
+	for name, group := range c.RateLimit.Groups {
+		key := "ratelimit.groups." + name
+		if group.Requests <= 0 {
+			invalid(key+".requests", "must be a positive number")
+		}
+		if group.Window <= 0 {
+			invalid(key+".window", "must be a positive number of seconds")
+		}
+		if !oneOf(group.Key, rateKeys) {
+			invalid(key+".key", "must be ip, user or api-key")
+		}
+	}
+
+	if c.Webhooks.Enabled {
+		if c.Webhooks.Interval <= 0 {
+			invalid("webhooks.interval", "must be a positive number of seconds")
+		}
+		if c.Webhooks.BatchSize <= 0 {
+			invalid("webhooks.batch-size", "must be a positive number")
+		}
+		if c.Webhooks.Timeout <= 0 {
+			invalid("webhooks.timeout", "must be a positive number of seconds")
+		}
+		if c.Webhooks.MaxAttempts <= 0 {
+			invalid("webhooks.max-attempts", "must be a positive number")
+		}
+		if c.Webhooks.BackoffBase <= 0 {
+			invalid("webhooks.backoff-base", "must be a positive number of seconds")
+		} else if c.Webhooks.BackoffMax < c.Webhooks.BackoffBase {
+			invalid("webhooks.backoff-max", "can not be less than webhooks.backoff-base")
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validOrigin accepts *, a scheme and host, or a scheme and a host whose
+// first label is * to match the subdomains.
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || scheme == "" {
+		return false
+	}
+	host = strings.TrimPrefix(host, "*.")
+	parsed, err := url.Parse(scheme + "://" + host)
+	return err == nil && parsed.Host == host && host != "" && !strings.Contains(host, "*")
+}
+
+func validPort(port int) bool {
+	return port >= 0 && port <= 65535
+}
+
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
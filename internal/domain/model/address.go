@@ -0,0 +1,18 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type Address struct {
+	bun.BaseModel `bun:"address,alias:address"`
+	ID            uuid.UUID `bun:"id,pk"`
+	UserID        uuid.UUID `bun:"user_id"`
+	AddressLine1  string    `bun:"address_line_1"`
+	AddressLine2  string    `bun:"address_line_2"`
+	ZipCode       string    `bun:"zip_code"`
+	Country       string    `bun:"country"`
+	City          string    `bun:"city"`
+	State         string    `bun:"state"`
+}
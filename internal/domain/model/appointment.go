@@ -0,0 +1,37 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type AppointmentStatus string
+
+var (
+	AppointmentScheduled AppointmentStatus = "scheduled"
+	AppointmentCompleted AppointmentStatus = "completed"
+	AppointmentCancelled AppointmentStatus = "cancelled"
+)
+
+const CancellationPatientDeceased = "patient deceased"
+
+type Appointment struct {
+	bun.BaseModel      `bun:"medical_appointments,alias:appointments"`
+	ID                 uuid.UUID         `bun:"id,pk"`
+	Date               time.Time         `bun:"date"`
+	PatientID          uuid.UUID         `bun:"patient_id"`
+	DoctorID           uuid.UUID         `bun:"doctor_id"`
+	ServiceID          uuid.NullUUID     `bun:"service_id"`
+	PackageID          uuid.NullUUID     `bun:"package_id"`
+	Total              float64           `bun:"total"`
+	Subtotal           float64           `bun:"subtotal"`
+	PaymentAt          sql.NullTime      `bun:"payment_at"`
+	CreatedAt          time.Time         `bun:"created_at"`
+	UpdateAt           time.Time         `bun:"update_at"`
+	Status             AppointmentStatus `bun:"status"`
+	CancelledAt        sql.NullTime      `bun:"cancelled_at"`
+	CancellationReason sql.NullString    `bun:"cancellation_reason"`
+}
@@ -0,0 +1,132 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type AuditAction string
+
+var (
+	AuditUserCreated     AuditAction = "user.created"
+	AuditPasswordReset   AuditAction = "user.password_reset"
+	AuditSessionsRevoked AuditAction = "user.sessions_revoked"
+	AuditLogin           AuditAction = "auth.login"
+	AuditLoginFailed     AuditAction = "auth.login_failed"
+	AuditRoleChanged     AuditAction = "user.role_changed"
+	AuditProfileUpdated  AuditAction = "user.profile_updated"
+	AuditRecordAccessed  AuditAction = "user.record_accessed"
+	AuditDeletionRequest AuditAction = "user.deletion_requested"
+	AuditDeletionCancel  AuditAction = "user.deletion_cancelled"
+	AuditPatientDeceased AuditAction = "patient.deceased"
+	AuditUserAnonymized  AuditAction = "user.anonymized"
+
+	AuditServiceAccountCreated AuditAction = "service_account.created"
+	AuditAPIKeyCreated         AuditAction = "api_key.created"
+	AuditAPIKeyRotated         AuditAction = "api_key.rotated"
+	AuditAPIKeyRevoked         AuditAction = "api_key.revoked"
+	AuditWebhookCreated        AuditAction = "webhook.created"
+	AuditWebhookDeleted        AuditAction = "webhook.deleted"
+	AuditWebhookRetried        AuditAction = "webhook.delivery_retried"
+)
+
+const auditGenesisPrevHash = "genesis"
+
+// AuditEvent is a row of the tamper-evident audit log, every event stores the
+// hash of the previous one so any modification breaks the chain.
+type AuditEvent struct {
+	bun.BaseModel `bun:"audit_events,alias:audit"`
+	ID            uuid.UUID         `bun:"id,pk"`
+	Seq           int64             `bun:"seq,scanonly"`
+	Action        AuditAction       `bun:"action"`
+	ActorID       uuid.NullUUID     `bun:"actor_id"`
+	TargetID      uuid.NullUUID     `bun:"target_id"`
+	IP            string            `bun:"ip"`
+	RequestID     string            `bun:"request_id"`
+	Metadata      map[string]string `bun:"metadata,type:jsonb"`
+	CreatedAt     time.Time         `bun:"created_at"`
+	PrevHash      string            `bun:"prev_hash"`
+	Hash          string            `bun:"hash"`
+}
+
+type AuditFilter struct {
+	ActorID  uuid.NullUUID
+	TargetID uuid.NullUUID
+	Action   string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+func NewAuditEvent(action AuditAction, actorID, targetID uuid.UUID) *AuditEvent {
+	return &AuditEvent{
+		ID:       uuid.New(),
+		Action:   action,
+		ActorID:  uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+		TargetID: uuid.NullUUID{UUID: targetID, Valid: targetID != uuid.Nil},
+		Metadata: map[string]string{},
+		// postgres keeps microseconds, truncate so the hash can be recomputed
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+}
+
+// Seal links the event to the previous one in the chain.
+func (e *AuditEvent) Seal(prevHash string) {
+	if prevHash == "" {
+		prevHash = auditGenesisPrevHash
+	}
+	e.PrevHash = prevHash
+	e.Hash = e.ComputeHash()
+}
+
+func (e *AuditEvent) ComputeHash() string {
+	metadata := []byte("{}")
+	if len(e.Metadata) > 0 {
+		metadata, _ = json.Marshal(e.Metadata)
+	}
+	content := struct {
+		ID        string          `json:"id"`
+		Action    string          `json:"action"`
+		ActorID   string          `json:"actor_id"`
+		TargetID  string          `json:"target_id"`
+		IP        string          `json:"ip"`
+		RequestID string          `json:"request_id"`
+		Metadata  json.RawMessage `json:"metadata"`
+		CreatedAt string          `json:"created_at"`
+		PrevHash  string          `json:"prev_hash"`
+	}{
+		ID:        e.ID.String(),
+		Action:    string(e.Action),
+		ActorID:   nullUUIDString(e.ActorID),
+		TargetID:  nullUUIDString(e.TargetID),
+		IP:        e.IP,
+		RequestID: e.RequestID,
+		Metadata:  metadata,
+		CreatedAt: e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		PrevHash:  e.PrevHash,
+	}
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks the event is untouched and follows prevHash.
+func (e *AuditEvent) Verify(prevHash string) bool {
+	if prevHash == "" {
+		prevHash = auditGenesisPrevHash
+	}
+	return e.PrevHash == prevHash && e.Hash == e.ComputeHash()
+}
+
+func nullUUIDString(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
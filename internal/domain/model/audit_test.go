@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEventChain(t *testing.T) {
+	first := NewAuditEvent(AuditLogin, uuid.New(), uuid.New())
+	first.Seal("")
+	second := NewAuditEvent(AuditRoleChanged, uuid.New(), uuid.New())
+	second.Metadata["to"] = string(AdminRole)
+	second.Seal(first.Hash)
+
+	assert.True(t, first.Verify(""))
+	assert.True(t, second.Verify(first.Hash))
+
+	t.Run("detects modified events", func(t *testing.T) {
+		second.Metadata["to"] = string(PatientRole)
+		assert.False(t, second.Verify(first.Hash))
+		second.Metadata["to"] = string(AdminRole)
+	})
+
+	t.Run("detects removed events", func(t *testing.T) {
+		assert.False(t, second.Verify(""))
+	})
+}
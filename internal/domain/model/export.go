@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// UserDataExport is everything we hold about a user, used for data portability requests.
+type UserDataExport struct {
+	ExportedAt   time.Time
+	User         *User
+	Addresses    []*Address
+	Insurance    []*MedicalInsurance
+	Appointments []*Appointment
+	Sessions     []*RefreshToken
+}
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type MedicalInsurance struct {
+	bun.BaseModel        `bun:"medical_ensure,alias:medical_ensure"`
+	ID                   uuid.UUID `bun:"id,pk"`
+	UserID               uuid.UUID `bun:"user_id"`
+	Name                 string    `bun:"name"`
+	Institution          string    `bun:"institution"`
+	SocialSecurityNumber string    `bun:"social_security_number"`
+}
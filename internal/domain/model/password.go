@@ -5,11 +5,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/pkg/apperror"
 	"github.com/uptrace/bun"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var ErrorPasswordIncorrect = errors.New("invalid credentials")
+var ErrorPasswordIncorrect = apperror.Unauthorized("invalid_credentials", "invalid credentials")
 
 type Password struct {
 	bun.BaseModel `bun:"user_passwords,alias:password"`
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/uptrace/bun"
+)
+
+// Scope is a permission granted to an api key, the users get theirs from
+// their role.
+type Scope string
+
+var (
+	ScopePatientsRead  Scope = "patients:read"
+	ScopePatientsWrite Scope = "patients:write"
+	ScopeAuditRead     Scope = "audit:read"
+)
+
+var Scopes = []Scope{ScopePatientsRead, ScopePatientsWrite, ScopeAuditRead}
+
+var (
+	ErrInvalidScope  = apperror.Validation("invalid_scope", "invalid scope %s")
+	ErrAPIKeyRevoked = apperror.Conflict("api_key_revoked", "api key is already revoked")
+)
+
+// APIKeyPrefix starts every api key, so they are easy to find by the secret
+// scanners.
+const APIKeyPrefix = "vc_"
+
+// ServiceAccount is a machine client, like the lab or the billing
+// integrations, it authenticates with its api keys.
+type ServiceAccount struct {
+	bun.BaseModel `bun:"service_accounts,alias:service_accounts"`
+	ID            uuid.UUID `bun:"id,pk"`
+	Name          string    `bun:"name"`
+	Description   string    `bun:"description"`
+	CreatedBy     uuid.UUID `bun:"created_by"`
+	CreatedAt     time.Time `bun:"created_at"`
+}
+
+func NewServiceAccount(name, description string, createdBy uuid.UUID) *ServiceAccount {
+	return &ServiceAccount{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: description,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// APIKey is the credential of a service account. The key given to the client
+// is vc_<prefix>.<secret>, the prefix finds the key and only the hash of the
+// secret is stored.
+type APIKey struct {
+	bun.BaseModel    `bun:"api_keys,alias:api_keys"`
+	ID               uuid.UUID `bun:"id,pk"`
+	ServiceAccountID uuid.UUID `bun:"service_account_id"`
+	Name             string    `bun:"name"`
+	Prefix           string    `bun:"prefix"`
+	Hash             string    `bun:"hash"`
+	Scopes           []Scope   `bun:"scopes,array"`
+	// RateLimit is the number of requests per minute, 0 uses the limit of
+	// the route group
+	RateLimit  int          `bun:"rate_limit"`
+	CreatedAt  time.Time    `bun:"created_at"`
+	ExpiresAt  sql.NullTime `bun:"expires_at"`
+	RevokedAt  sql.NullTime `bun:"revoked_at"`
+	LastUsedAt sql.NullTime `bun:"last_used_at"`
+}
+
+// NewAPIKey returns the key and the value to give to the client, it can not
+// be recovered later.
+func NewAPIKey(serviceAccountID uuid.UUID, name, prefix, secret string, scopes []Scope, rateLimit int) (*APIKey, string, error) {
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, "", ErrInvalidScope.WithArgs(scope)
+		}
+	}
+	key := &APIKey{
+		ID:               uuid.New(),
+		ServiceAccountID: serviceAccountID,
+		Name:             name,
+		Prefix:           prefix,
+		Hash:             hashAPIKeySecret(secret),
+		Scopes:           slices.Clone(scopes),
+		RateLimit:        rateLimit,
+		CreatedAt:        time.Now(),
+	}
+	return key, APIKeyPrefix + prefix + "." + secret, nil
+}
+
+// ParseAPIKey splits the value given by the client in its prefix and secret.
+func ParseAPIKey(value string) (prefix, secret string, ok bool) {
+	value, ok = strings.CutPrefix(value, APIKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	prefix, secret, ok = strings.Cut(value, ".")
+	return prefix, secret, ok && prefix != "" && secret != ""
+}
+
+// the secrets are random, a fast hash is enough to not store them
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *APIKey) Verify(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hashAPIKeySecret(secret))) == 1
+}
+
+// IsActive is false once the key is revoked or expired.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt.Valid {
+		return false
+	}
+	return !k.ExpiresAt.Valid || now.Before(k.ExpiresAt.Time)
+}
+
+func (k *APIKey) HasScope(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+func (k *APIKey) Revoke(now time.Time) error {
+	if k.RevokedAt.Valid {
+		return ErrAPIKeyRevoked
+	}
+	k.RevokedAt = sql.NullTime{Time: now, Valid: true}
+	return nil
+}
+
+// ExpireAt shortens the life of the key, a later expiration is ignored.
+func (k *APIKey) ExpireAt(at time.Time) {
+	if k.ExpiresAt.Valid && k.ExpiresAt.Time.Before(at) {
+		return
+	}
+	k.ExpiresAt = sql.NullTime{Time: at, Valid: true}
+}
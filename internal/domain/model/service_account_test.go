@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	_, _, err := NewAPIKey(uuid.New(), "lab", "abc", "secret", []Scope{"patients:delete"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	key, value, err := NewAPIKey(uuid.New(), "lab", "abc", "secret", []Scope{ScopePatientsRead}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "vc_abc.secret", value)
+	assert.NotContains(t, key.Hash, "secret")
+
+	prefix, secret, ok := ParseAPIKey(value)
+	require.True(t, ok)
+	assert.Equal(t, "abc", prefix)
+	assert.True(t, key.Verify(secret))
+	assert.False(t, key.Verify("other"))
+	for _, invalid := range []string{"abc.secret", "vc_abc", "vc_.secret", "vc_abc."} {
+		_, _, ok := ParseAPIKey(invalid)
+		assert.False(t, ok, invalid)
+	}
+
+	assert.True(t, key.HasScope(ScopePatientsRead))
+	assert.False(t, key.HasScope(ScopeAuditRead))
+
+	now := time.Now()
+	assert.True(t, key.IsActive(now))
+	key.ExpireAt(now.Add(time.Hour))
+	key.ExpireAt(now.Add(2 * time.Hour))
+	assert.True(t, key.IsActive(now))
+	assert.False(t, key.IsActive(now.Add(time.Hour)), "a later expiration is ignored")
+	require.NoError(t, key.Revoke(now))
+	assert.False(t, key.IsActive(now))
+	assert.ErrorIs(t, key.Revoke(now), ErrAPIKeyRevoked)
+}
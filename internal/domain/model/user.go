@@ -2,20 +2,29 @@ package model
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/i18n"
 	"github.com/uptrace/bun"
 )
 
 type UserRole string
 
 var (
-	ErrInvalidRole = errors.New("invalid role")
+	ErrInvalidRole              = apperror.Validation("invalid_role", "invalid role")
+	ErrDeletionAlreadyRequested = apperror.Conflict("deletion_already_requested", "account deletion already requested")
+	ErrDeletionNotRequested     = apperror.Conflict("deletion_not_requested", "account deletion was not requested")
+	ErrAlreadyDeceased          = apperror.Conflict("already_deceased", "user is already marked as deceased")
+	ErrInvalidDeceasedDate      = apperror.Validation("invalid_deceased_date", "date of death can not be in the future")
+	ErrUserInactive             = apperror.Forbidden("user_inactive", "user account is inactive")
 )
 
+const anonymizedEmailDomain = "anonymized.invalid"
+
 var (
 	AdminRole     UserRole = "admin"
 	DoctorRole    UserRole = "doctor"
@@ -25,32 +34,49 @@ var (
 
 type User struct {
 	bun.BaseModel `bun:"users,alias:users"`
-	ID            uuid.UUID    `bun:"id,pk"`
-	Email         string       `bun:"email"`
-	FirstName     string       `bun:"first_name"`
-	LastName      string       `bun:"last_name"`
-	Rol           UserRole     `bun:"rol"`
-	DNI           string       `bun:"dni"`
-	Birthdate     time.Time    `bun:"birthdate"`
-	Phone         string       `bun:"phone"`
-	IsActive      bool         `bun:"is_active"`
-	DeceasedAt    sql.NullTime `bun:"deceased_at"`
-	CreatedAt     time.Time    `bun:"created_at"`
-	UpdateAt      time.Time    `bun:"update_at"`
-	Password      *Password    `bun:"rel:has-one,join:id=user_id"`
+	ID            uuid.UUID `bun:"id,pk"`
+	Email         string    `bun:"email"`
+	FirstName     string    `bun:"first_name"`
+	LastName      string    `bun:"last_name"`
+	Rol           UserRole  `bun:"rol"`
+	DNI           string    `bun:"dni"`
+	Birthdate     time.Time `bun:"birthdate,nullzero"`
+	Phone         string    `bun:"phone"`
+	// Language is the preferred locale of the user for emails and
+	// notifications, the api answers in the language of the request.
+	Language   string       `bun:"language"`
+	IsActive   bool         `bun:"is_active"`
+	DeceasedAt sql.NullTime `bun:"deceased_at"`
+	CreatedAt  time.Time    `bun:"created_at"`
+	UpdateAt   time.Time    `bun:"update_at"`
+	// DeletionRequestedAt is set when the user asks for erasure, the account
+	// is anonymized once the grace period is over.
+	DeletionRequestedAt sql.NullTime `bun:"deletion_requested_at"`
+	AnonymizedAt        sql.NullTime `bun:"anonymized_at"`
+	Password            *Password    `bun:"rel:has-one,join:id=user_id"`
 }
 
 func NewPatientUser(dto dto.UserDto) *User {
+	return NewUser(dto, PatientRole)
+}
+
+// NewUser creates a user with any role, the api only creates patients but
+// the cli bootstraps admins and seeds staff.
+func NewUser(dto dto.UserDto, role UserRole) *User {
 	user := &User{
 		ID:        uuid.New(),
 		Email:     dto.Email,
 		FirstName: dto.FirstName,
 		LastName:  dto.LastName,
-		Rol:       PatientRole,
+		Rol:       role,
+		Language:  dto.Language,
 		IsActive:  true,
 		CreatedAt: time.Now(),
 		UpdateAt:  time.Now(),
 	}
+	if user.Language == "" {
+		user.Language = i18n.DefaultLocale
+	}
 	password := NewPassword(user.ID, dto.Password)
 	_ = password.SetHash()
 	user.Password = password
@@ -78,3 +104,62 @@ func (u *User) UpdateRole(role string) error {
 	u.UpdateAt = time.Now()
 	return nil
 }
+
+func (u *User) RequestDeletion() error {
+	if u.DeletionRequestedAt.Valid {
+		return ErrDeletionAlreadyRequested
+	}
+	u.DeletionRequestedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdateAt = time.Now()
+	return nil
+}
+
+func (u *User) CancelDeletion() error {
+	if !u.DeletionRequestedAt.Valid || u.IsAnonymized() {
+		return ErrDeletionNotRequested
+	}
+	u.DeletionRequestedAt = sql.NullTime{}
+	u.UpdateAt = time.Now()
+	return nil
+}
+
+func (u *User) IsAnonymized() bool {
+	return u.AnonymizedAt.Valid
+}
+
+// Anonymize removes the personal data of the user, the row is kept so the
+// clinical and billing records that reference it stay valid.
+func (u *User) Anonymize() {
+	u.Email = fmt.Sprintf("deleted-%s@%s", u.ID, anonymizedEmailDomain)
+	u.FirstName = ""
+	u.LastName = ""
+	u.DNI = ""
+	u.Phone = ""
+	u.Birthdate = time.Time{}
+	u.IsActive = false
+	u.AnonymizedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdateAt = time.Now()
+}
+
+func (u *User) IsDeceased() bool {
+	return u.DeceasedAt.Valid
+}
+
+// MarkDeceased records the date of death and deactivates the account.
+func (u *User) MarkDeceased(date time.Time) error {
+	if u.IsDeceased() {
+		return ErrAlreadyDeceased
+	}
+	if date.After(time.Now()) {
+		return ErrInvalidDeceasedDate
+	}
+	u.DeceasedAt = sql.NullTime{Time: date, Valid: true}
+	u.IsActive = false
+	u.UpdateAt = time.Now()
+	return nil
+}
+
+// CanAuthenticate reports if the user is allowed to log in or renew a session.
+func (u *User) CanAuthenticate() bool {
+	return !u.IsDeceased() && !u.IsAnonymized()
+}
@@ -0,0 +1,218 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/uptrace/bun"
+)
+
+// WebhookEvent is a domain event the other systems can subscribe to.
+type WebhookEvent string
+
+var (
+	WebhookPatientRegistered    WebhookEvent = "patient.registered"
+	WebhookUserRoleChanged      WebhookEvent = "user.role_changed"
+	WebhookAppointmentCancelled WebhookEvent = "appointment.cancelled"
+)
+
+// WebhookEvents are the events that can be subscribed to, only the ones
+// something publishes. The bookings and payments join them with the services
+// that record them.
+var WebhookEvents = []WebhookEvent{
+	WebhookPatientRegistered,
+	WebhookUserRoleChanged,
+	WebhookAppointmentCancelled,
+}
+
+type WebhookStatus string
+
+var (
+	WebhookPending   WebhookStatus = "pending"
+	WebhookDelivered WebhookStatus = "delivered"
+	// WebhookDead deliveries ran out of attempts, an admin can retry them
+	WebhookDead WebhookStatus = "dead"
+)
+
+var (
+	ErrInvalidWebhookEvent = apperror.Validation("invalid_webhook_event", "invalid webhook event %s")
+	ErrWebhookNotDead      = apperror.Conflict("webhook_delivery_not_dead", "only dead deliveries can be retried")
+)
+
+// WebhookSignatureHeader carries t=<unix time>,v1=<hex hmac>, the hmac is the
+// sha256 of "<unix time>.<body>" keyed by the secret of the subscription.
+const WebhookSignatureHeader = "X-Vitacare-Signature"
+
+// WebhookSubscription sends the events it is subscribed to to its url. The
+// secret signs the deliveries so it is stored as is, unlike the api keys.
+type WebhookSubscription struct {
+	bun.BaseModel `bun:"webhook_subscriptions,alias:webhook_subscriptions"`
+	ID            uuid.UUID      `bun:"id,pk"`
+	URL           string         `bun:"url"`
+	Description   string         `bun:"description"`
+	Events        []WebhookEvent `bun:"events,array"`
+	Secret        string         `bun:"secret"`
+	CreatedBy     uuid.UUID      `bun:"created_by"`
+	CreatedAt     time.Time      `bun:"created_at"`
+}
+
+func NewWebhookSubscription(url, description, secret string, events []WebhookEvent, createdBy uuid.UUID) (*WebhookSubscription, error) {
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return nil, ErrInvalidWebhookEvent.WithArgs(event)
+		}
+	}
+	return &WebhookSubscription{
+		ID:          uuid.New(),
+		URL:         url,
+		Description: description,
+		Events:      slices.Clone(events),
+		Secret:      secret,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+func (s *WebhookSubscription) Subscribed(event WebhookEvent) bool {
+	return slices.Contains(s.Events, event)
+}
+
+// Sign returns the value of WebhookSignatureHeader for body sent at t.
+func (s *WebhookSubscription) Sign(t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// WebhookPayload is the body of every delivery.
+type WebhookPayload struct {
+	ID        uuid.UUID    `json:"id"`
+	Event     WebhookEvent `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+	Data      any          `json:"data"`
+}
+
+// PatientRegisteredData is the data of WebhookPatientRegistered. The payloads
+// are kept in the delivery log, so it has no personal data, the subscribers
+// read the patient with an api key.
+type PatientRegisteredData struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoleChangedData is the data of WebhookUserRoleChanged.
+type RoleChangedData struct {
+	ID   uuid.UUID `json:"id"`
+	From UserRole  `json:"from"`
+	To   UserRole  `json:"to"`
+}
+
+// AppointmentCancelledData is the data of WebhookAppointmentCancelled.
+type AppointmentCancelledData struct {
+	ID          uuid.UUID `json:"id"`
+	PatientID   uuid.UUID `json:"patient_id"`
+	DoctorID    uuid.UUID `json:"doctor_id"`
+	Date        time.Time `json:"date"`
+	CancelledAt time.Time `json:"cancelled_at"`
+	Reason      string    `json:"reason"`
+}
+
+// WebhookRetryPolicy spaces the attempts of a delivery exponentially.
+type WebhookRetryPolicy struct {
+	MaxAttempts int
+	Base        time.Duration
+	Max         time.Duration
+}
+
+// Delay is the wait after the failed attempt number attempt, starting at 1.
+func (p WebhookRetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Base
+	for i := 1; i < attempt && delay < p.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, p.Max)
+}
+
+// WebhookDelivery is the log of the attempts to send an event to a
+// subscription.
+type WebhookDelivery struct {
+	bun.BaseModel  `bun:"webhook_deliveries,alias:webhook_deliveries"`
+	ID             uuid.UUID `bun:"id,pk"`
+	SubscriptionID uuid.UUID `bun:"subscription_id"`
+	// EventID is shared by the deliveries of the same event
+	EventID        uuid.UUID       `bun:"event_id"`
+	Event          WebhookEvent    `bun:"event"`
+	Payload        json.RawMessage `bun:"payload,type:jsonb"`
+	Status         WebhookStatus   `bun:"status"`
+	Attempts       int             `bun:"attempts"`
+	NextAttemptAt  time.Time       `bun:"next_attempt_at"`
+	ResponseStatus int             `bun:"response_status"`
+	LastError      string          `bun:"last_error"`
+	CreatedAt      time.Time       `bun:"created_at"`
+	DeliveredAt    sql.NullTime    `bun:"delivered_at"`
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID uuid.NullUUID
+	Event          string
+	Status         string
+	Limit          int
+	Offset         int
+}
+
+func NewWebhookDelivery(subscriptionID uuid.UUID, payload WebhookPayload, body []byte) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: subscriptionID,
+		EventID:        payload.ID,
+		Event:          payload.Event,
+		Payload:        body,
+		Status:         WebhookPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+}
+
+func (d *WebhookDelivery) Succeed(now time.Time, responseStatus int) {
+	d.Attempts++
+	d.Status = WebhookDelivered
+	d.ResponseStatus = responseStatus
+	d.LastError = ""
+	d.DeliveredAt = sql.NullTime{Time: now, Valid: true}
+}
+
+// Fail schedules the next attempt, the delivery is dead once it used all the
+// attempts of policy.
+func (d *WebhookDelivery) Fail(now time.Time, responseStatus int, reason string, policy WebhookRetryPolicy) {
+	d.Attempts++
+	d.ResponseStatus = responseStatus
+	d.LastError = reason
+	if d.Attempts >= policy.MaxAttempts {
+		d.Status = WebhookDead
+		return
+	}
+	d.NextAttemptAt = now.Add(policy.Delay(d.Attempts))
+}
+
+// Retry gives a dead delivery a new set of attempts.
+func (d *WebhookDelivery) Retry(now time.Time) error {
+	if d.Status != WebhookDead {
+		return ErrWebhookNotDead
+	}
+	d.Status = WebhookPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	return nil
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSubscription(t *testing.T) {
+	_, err := NewWebhookSubscription("https://lab.test", "", "secret", []WebhookEvent{"user.deleted"}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidWebhookEvent)
+
+	subscription, err := NewWebhookSubscription("https://lab.test", "", "secret", []WebhookEvent{WebhookPatientRegistered}, uuid.New())
+	require.NoError(t, err)
+	assert.True(t, subscription.Subscribed(WebhookPatientRegistered))
+	assert.False(t, subscription.Subscribed(WebhookAppointmentCancelled))
+
+	_, err = NewWebhookSubscription("https://lab.test", "", "secret", []WebhookEvent{"payment.recorded"}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidWebhookEvent, "nothing publishes the payments yet")
+
+	body := []byte(`{"event":"patient.registered"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	expected := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+	assert.Equal(t, expected, subscription.Sign(time.Unix(1700000000, 0), body))
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	policy := WebhookRetryPolicy{MaxAttempts: 3, Base: 30 * time.Second, Max: time.Minute}
+	assert.Equal(t, 30*time.Second, policy.Delay(1))
+	assert.Equal(t, time.Minute, policy.Delay(2))
+	assert.Equal(t, time.Minute, policy.Delay(10), "the delay is capped")
+
+	now := time.Now()
+	delivery := NewWebhookDelivery(uuid.New(), WebhookPayload{ID: uuid.New(), Event: WebhookPatientRegistered}, []byte(`{}`))
+	assert.ErrorIs(t, delivery.Retry(now), ErrWebhookNotDead)
+
+	delivery.Fail(now, 500, "unexpected status 500", policy)
+	assert.Equal(t, WebhookPending, delivery.Status)
+	assert.Equal(t, now.Add(30*time.Second), delivery.NextAttemptAt)
+	delivery.Fail(now, 0, "timeout", policy)
+	delivery.Fail(now, 0, "timeout", policy)
+	assert.Equal(t, WebhookDead, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+
+	require.NoError(t, delivery.Retry(now))
+	assert.Equal(t, WebhookPending, delivery.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+	delivery.Succeed(now, 204)
+	assert.Equal(t, WebhookDelivered, delivery.Status)
+	assert.True(t, delivery.DeliveredAt.Valid)
+	assert.Empty(t, delivery.LastError)
+}
@@ -0,0 +1,34 @@
+package addressRepository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+type AddressRepo struct {
+	DB *db.DBRepository
+}
+
+func NewAddressRepository(db *db.DBRepository) *AddressRepo {
+	return &AddressRepo{
+		DB: db,
+	}
+}
+
+func (a *AddressRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Address, error) {
+	var addresses []*model.Address
+	q := a.DB.Querier(ctx).NewSelect().Model(&addresses).Where("user_id = ?", userID)
+	err := q.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func (a *AddressRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	_, err := a.DB.Querier(ctx).NewDelete().Model((*model.Address)(nil)).Where("user_id = ?", userID).Exec(ctx)
+	return err
+}
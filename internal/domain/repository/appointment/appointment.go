@@ -0,0 +1,49 @@
+package appointmentRepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+type AppointmentRepo struct {
+	DB *db.DBRepository
+}
+
+func NewAppointmentRepository(db *db.DBRepository) *AppointmentRepo {
+	return &AppointmentRepo{
+		DB: db,
+	}
+}
+
+func (a *AppointmentRepo) GetByPatientID(ctx context.Context, patientID uuid.UUID) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	q := a.DB.Querier(ctx).NewSelect().Model(&appointments).Where("patient_id = ?", patientID).Order("date DESC")
+	err := q.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+func (a *AppointmentRepo) CancelFutureByPatientID(ctx context.Context, patientID uuid.UUID, reason string) ([]*model.Appointment, error) {
+	now := time.Now()
+	var appointments []*model.Appointment
+	err := a.DB.Querier(ctx).NewUpdate().Model((*model.Appointment)(nil)).
+		Set("status = ?", model.AppointmentCancelled).
+		Set("cancelled_at = ?", now).
+		Set("cancellation_reason = ?", reason).
+		Set("update_at = ?", now).
+		Where("patient_id = ?", patientID).
+		Where("date > ?", now).
+		Where("status = ?", model.AppointmentScheduled).
+		Returning("*").
+		Scan(ctx, &appointments)
+	if err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
@@ -0,0 +1,86 @@
+package auditRepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+// auditChainLock is the key of the advisory lock that serializes the appends
+// so two events never link to the same previous hash.
+const auditChainLock = 7310001
+
+type AuditRepo struct {
+	DB *db.DBRepository
+}
+
+func NewAuditRepository(db *db.DBRepository) *AuditRepo {
+	return &AuditRepo{
+		DB: db,
+	}
+}
+
+func (a *AuditRepo) Append(ctx context.Context, event *model.AuditEvent) error {
+	// inside the transaction of the caller the event is rolled back with it
+	return a.DB.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := a.DB.Querier(ctx)
+		_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(?)", auditChainLock)
+		if err != nil {
+			return err
+		}
+		var prevHash string
+		err = tx.NewSelect().Model((*model.AuditEvent)(nil)).
+			Column("hash").
+			Order("seq DESC").
+			Limit(1).
+			Scan(ctx, &prevHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		event.Seal(prevHash)
+		_, err = tx.NewInsert().Model(event).Returning("seq").Exec(ctx)
+		return err
+	})
+}
+
+func (a *AuditRepo) List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, int, error) {
+	var events []*model.AuditEvent
+	q := a.DB.Reader(ctx).NewSelect().Model(&events)
+	if filter.ActorID.Valid {
+		q = q.Where("actor_id = ?", filter.ActorID.UUID)
+	}
+	if filter.TargetID.Valid {
+		q = q.Where("target_id = ?", filter.TargetID.UUID)
+	}
+	if filter.Action != "" {
+		q = q.Where("action = ?", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		q = q.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q = q.Where("created_at < ?", filter.To)
+	}
+	count, err := q.Order("seq DESC").Limit(filter.Limit).Offset(filter.Offset).ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, count, nil
+}
+
+// GetChain returns the events after seq in insertion order.
+func (a *AuditRepo) GetChain(ctx context.Context, afterSeq int64, limit int) ([]*model.AuditEvent, error) {
+	var events []*model.AuditEvent
+	err := a.DB.Reader(ctx).NewSelect().Model(&events).
+		Where("seq > ?", afterSeq).
+		Order("seq ASC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
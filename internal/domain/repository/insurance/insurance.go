@@ -0,0 +1,29 @@
+package insuranceRepository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+type InsuranceRepo struct {
+	DB *db.DBRepository
+}
+
+func NewInsuranceRepository(db *db.DBRepository) *InsuranceRepo {
+	return &InsuranceRepo{
+		DB: db,
+	}
+}
+
+func (i *InsuranceRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.MedicalInsurance, error) {
+	var insurance []*model.MedicalInsurance
+	q := i.DB.Querier(ctx).NewSelect().Model(&insurance).Where("user_id = ?", userID)
+	err := q.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return insurance, nil
+}
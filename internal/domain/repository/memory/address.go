@@ -0,0 +1,41 @@
+package memoryRepository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type AddressRepo struct {
+	store *Store
+}
+
+func NewAddressRepository(store *Store) *AddressRepo {
+	return &AddressRepo{
+		store: store,
+	}
+}
+
+func (a *AddressRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Address, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	var addresses []*model.Address
+	for _, address := range a.store.addresses {
+		if address.UserID == userID {
+			addresses = append(addresses, &address)
+		}
+	}
+	return addresses, nil
+}
+
+func (a *AddressRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	for id, address := range a.store.addresses {
+		if address.UserID == userID {
+			delete(a.store.addresses, id)
+		}
+	}
+	return nil
+}
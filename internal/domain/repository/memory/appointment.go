@@ -0,0 +1,55 @@
+package memoryRepository
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type AppointmentRepo struct {
+	store *Store
+}
+
+func NewAppointmentRepository(store *Store) *AppointmentRepo {
+	return &AppointmentRepo{
+		store: store,
+	}
+}
+
+func (a *AppointmentRepo) GetByPatientID(ctx context.Context, patientID uuid.UUID) ([]*model.Appointment, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	var appointments []*model.Appointment
+	for _, appointment := range a.store.appointments {
+		if appointment.PatientID == patientID {
+			appointments = append(appointments, &appointment)
+		}
+	}
+	slices.SortFunc(appointments, func(x, y *model.Appointment) int {
+		return y.Date.Compare(x.Date)
+	})
+	return appointments, nil
+}
+
+func (a *AppointmentRepo) CancelFutureByPatientID(ctx context.Context, patientID uuid.UUID, reason string) ([]*model.Appointment, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	now := time.Now()
+	var cancelled []*model.Appointment
+	for id, appointment := range a.store.appointments {
+		if appointment.PatientID != patientID || !appointment.Date.After(now) || appointment.Status != model.AppointmentScheduled {
+			continue
+		}
+		appointment.Status = model.AppointmentCancelled
+		appointment.CancelledAt = sql.NullTime{Time: now, Valid: true}
+		appointment.CancellationReason = sql.NullString{String: reason, Valid: true}
+		appointment.UpdateAt = now
+		a.store.appointments[id] = appointment
+		cancelled = append(cancelled, &appointment)
+	}
+	return cancelled, nil
+}
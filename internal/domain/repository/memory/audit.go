@@ -0,0 +1,68 @@
+package memoryRepository
+
+import (
+	"context"
+
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type AuditRepo struct {
+	store *Store
+}
+
+func NewAuditRepository(store *Store) *AuditRepo {
+	return &AuditRepo{
+		store: store,
+	}
+}
+
+func (a *AuditRepo) Append(ctx context.Context, event *model.AuditEvent) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	prevHash := ""
+	if len(a.store.audit) > 0 {
+		prevHash = a.store.audit[len(a.store.audit)-1].Hash
+	}
+	event.Seal(prevHash)
+	event.Seq = int64(len(a.store.audit)) + 1
+	a.store.audit = append(a.store.audit, *event)
+	return nil
+}
+
+func (a *AuditRepo) List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, int, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	var events []*model.AuditEvent
+	for i := len(a.store.audit) - 1; i >= 0; i-- {
+		event := a.store.audit[i]
+		if filter.ActorID.Valid && event.ActorID != filter.ActorID {
+			continue
+		}
+		if filter.TargetID.Valid && event.TargetID != filter.TargetID {
+			continue
+		}
+		if filter.Action != "" && string(event.Action) != filter.Action {
+			continue
+		}
+		if !filter.From.IsZero() && event.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !event.CreatedAt.Before(filter.To) {
+			continue
+		}
+		events = append(events, &event)
+	}
+	return page(events, filter.Limit, filter.Offset), len(events), nil
+}
+
+func (a *AuditRepo) GetChain(ctx context.Context, afterSeq int64, limit int) ([]*model.AuditEvent, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	var events []*model.AuditEvent
+	for _, event := range a.store.audit {
+		if event.Seq > afterSeq {
+			events = append(events, &event)
+		}
+	}
+	return page(events, limit, 0), nil
+}
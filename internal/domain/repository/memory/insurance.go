@@ -0,0 +1,30 @@
+package memoryRepository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type InsuranceRepo struct {
+	store *Store
+}
+
+func NewInsuranceRepository(store *Store) *InsuranceRepo {
+	return &InsuranceRepo{
+		store: store,
+	}
+}
+
+func (i *InsuranceRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.MedicalInsurance, error) {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+	var insurance []*model.MedicalInsurance
+	for _, row := range i.store.insurance {
+		if row.UserID == userID {
+			insurance = append(insurance, &row)
+		}
+	}
+	return insurance, nil
+}
@@ -0,0 +1,42 @@
+package memoryRepository
+
+import (
+	"testing"
+
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+	"github.com/oaxacos/vitacare/internal/domain/repository/repositorytest"
+)
+
+var (
+	_ repository.UserRepository                = (*UserRepo)(nil)
+	_ repository.PasswordRepository            = (*PasswordRepo)(nil)
+	_ repository.RefreshTokenRepository        = (*RefreshTokenRepo)(nil)
+	_ repository.AddressRepository             = (*AddressRepo)(nil)
+	_ repository.InsuranceRepository           = (*InsuranceRepo)(nil)
+	_ repository.AppointmentRepository         = (*AppointmentRepo)(nil)
+	_ repository.AuditRepository               = (*AuditRepo)(nil)
+	_ repository.ServiceAccountRepository      = (*ServiceAccountRepo)(nil)
+	_ repository.APIKeyRepository              = (*APIKeyRepo)(nil)
+	_ repository.WebhookSubscriptionRepository = (*WebhookSubscriptionRepo)(nil)
+	_ repository.WebhookDeliveryRepository     = (*WebhookDeliveryRepo)(nil)
+)
+
+func TestMemoryRepositories(t *testing.T) {
+	repositoryTest.Run(t, func(t *testing.T) repositoryTest.Repositories {
+		store := NewStore()
+		return repositoryTest.Repositories{
+			User:         NewUserRepository(store),
+			Password:     NewPasswordRepository(store),
+			Token:        NewTokenRepository(store),
+			Address:      NewAddressRepository(store),
+			Insurance:    NewInsuranceRepository(store),
+			Appointments: NewAppointmentRepository(store),
+			Audit:        NewAuditRepository(store),
+			Accounts:     NewServiceAccountRepository(store),
+			APIKeys:      NewAPIKeyRepository(store),
+			Webhooks:     NewWebhookSubscriptionRepository(store),
+			Deliveries:   NewWebhookDeliveryRepository(store),
+			Seed:         store.Seed,
+		}
+	})
+}
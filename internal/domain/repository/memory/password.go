@@ -0,0 +1,58 @@
+package memoryRepository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type PasswordRepo struct {
+	store *Store
+}
+
+func NewPasswordRepository(store *Store) *PasswordRepo {
+	return &PasswordRepo{
+		store: store,
+	}
+}
+
+func (p *PasswordRepo) Save(ctx context.Context, password *model.Password) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.passwords[password.ID]; ok {
+		return ErrDuplicateKey
+	}
+	p.store.passwords[password.ID] = *password
+	return nil
+}
+
+func (p *PasswordRepo) VerifyPasswordText(ctx context.Context, userId uuid.UUID, plainText string) error {
+	p.store.mu.Lock()
+	var hash []byte
+	found := false
+	for _, password := range p.store.passwords {
+		if password.UserID == userId {
+			hash, found = password.Hash, true
+			break
+		}
+	}
+	p.store.mu.Unlock()
+
+	if !found {
+		return sql.ErrNoRows
+	}
+	return (&model.Password{}).VerifyPassword(plainText, hash)
+}
+
+func (p *PasswordRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	for id, password := range p.store.passwords {
+		if password.UserID == userID {
+			delete(p.store.passwords, id)
+		}
+	}
+	return nil
+}
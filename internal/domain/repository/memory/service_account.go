@@ -0,0 +1,150 @@
+package memoryRepository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type ServiceAccountRepo struct {
+	store *Store
+}
+
+func NewServiceAccountRepository(store *Store) *ServiceAccountRepo {
+	return &ServiceAccountRepo{
+		store: store,
+	}
+}
+
+func (s *ServiceAccountRepo) Save(ctx context.Context, account *model.ServiceAccount) error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	for id, existing := range s.store.accounts {
+		if id == account.ID || existing.Name == account.Name {
+			return ErrDuplicateKey
+		}
+	}
+	s.store.accounts[account.ID] = *account
+	return nil
+}
+
+func (s *ServiceAccountRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.ServiceAccount, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	account, ok := s.store.accounts[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &account, nil
+}
+
+func (s *ServiceAccountRepo) List(ctx context.Context) ([]*model.ServiceAccount, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	accounts := make([]*model.ServiceAccount, 0, len(s.store.accounts))
+	for _, account := range s.store.accounts {
+		accounts = append(accounts, &account)
+	}
+	slices.SortFunc(accounts, func(a, b *model.ServiceAccount) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return accounts, nil
+}
+
+type APIKeyRepo struct {
+	store *Store
+}
+
+func NewAPIKeyRepository(store *Store) *APIKeyRepo {
+	return &APIKeyRepo{
+		store: store,
+	}
+}
+
+func (a *APIKeyRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return a.store.WithinTransaction(ctx, fn)
+}
+
+func (a *APIKeyRepo) Save(ctx context.Context, key *model.APIKey) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	for id, existing := range a.store.apiKeys {
+		if id == key.ID || existing.Prefix == key.Prefix {
+			return ErrDuplicateKey
+		}
+	}
+	a.store.apiKeys[key.ID] = cloneAPIKey(key)
+	return nil
+}
+
+func (a *APIKeyRepo) Update(ctx context.Context, key *model.APIKey) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	if existing, ok := a.store.apiKeys[key.ID]; ok {
+		clone := cloneAPIKey(key)
+		clone.LastUsedAt = existing.LastUsedAt
+		a.store.apiKeys[key.ID] = clone
+	}
+	return nil
+}
+
+func (a *APIKeyRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	key, ok := a.store.apiKeys[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	key = cloneAPIKey(&key)
+	return &key, nil
+}
+
+func (a *APIKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	for _, key := range a.store.apiKeys {
+		if key.Prefix == prefix {
+			key = cloneAPIKey(&key)
+			return &key, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (a *APIKeyRepo) GetByServiceAccountID(ctx context.Context, serviceAccountID uuid.UUID) ([]*model.APIKey, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	var keys []*model.APIKey
+	for _, key := range a.store.apiKeys {
+		if key.ServiceAccountID == serviceAccountID {
+			key = cloneAPIKey(&key)
+			keys = append(keys, &key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b *model.APIKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return keys, nil
+}
+
+func (a *APIKeyRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	if key, ok := a.store.apiKeys[id]; ok {
+		key.LastUsedAt = sql.NullTime{Time: at, Valid: true}
+		a.store.apiKeys[id] = key
+	}
+	return nil
+}
+
+// cloneAPIKey copies the scopes so the callers can't modify the stored key.
+func cloneAPIKey(key *model.APIKey) model.APIKey {
+	clone := *key
+	clone.Scopes = slices.Clone(key.Scopes)
+	return clone
+}
@@ -0,0 +1,158 @@
+package memoryRepository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+)
+
+var ErrDuplicateKey = repository.ErrDuplicateKey
+
+// Store keeps the rows of every in-memory repository, the repositories built
+// on the same store share its transactions like the postgres ones share the
+// database. It is meant for tests, a transaction snapshots the whole store
+// so concurrent transactions are not isolated from each other.
+type Store struct {
+	mu           sync.Mutex
+	users        map[uuid.UUID]model.User
+	passwords    map[uuid.UUID]model.Password
+	tokens       map[uuid.UUID]model.RefreshToken
+	addresses    map[uuid.UUID]model.Address
+	insurance    map[uuid.UUID]model.MedicalInsurance
+	appointments map[uuid.UUID]model.Appointment
+	audit        []model.AuditEvent
+	accounts     map[uuid.UUID]model.ServiceAccount
+	apiKeys      map[uuid.UUID]model.APIKey
+	webhooks     map[uuid.UUID]model.WebhookSubscription
+	deliveries   map[uuid.UUID]model.WebhookDelivery
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:        map[uuid.UUID]model.User{},
+		passwords:    map[uuid.UUID]model.Password{},
+		tokens:       map[uuid.UUID]model.RefreshToken{},
+		addresses:    map[uuid.UUID]model.Address{},
+		insurance:    map[uuid.UUID]model.MedicalInsurance{},
+		appointments: map[uuid.UUID]model.Appointment{},
+		accounts:     map[uuid.UUID]model.ServiceAccount{},
+		apiKeys:      map[uuid.UUID]model.APIKey{},
+		webhooks:     map[uuid.UUID]model.WebhookSubscription{},
+		deliveries:   map[uuid.UUID]model.WebhookDelivery{},
+	}
+}
+
+// WithinTransaction restores the store as it was before fn when fn fails, a
+// nested call behaves like a savepoint.
+func (s *Store) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	snapshot := s.snapshot()
+	s.mu.Unlock()
+
+	err := fn(ctx)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.restore(snapshot)
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Seed inserts rows for the repositories that have no insert method yet,
+// like addresses or appointments.
+func (s *Store) Seed(ctx context.Context, rows ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range rows {
+		switch row := row.(type) {
+		case *model.User:
+			s.users[row.ID] = cloneUser(row)
+		case *model.Password:
+			s.passwords[row.ID] = *row
+		case *model.RefreshToken:
+			s.tokens[row.ID] = *row
+		case *model.Address:
+			s.addresses[row.ID] = *row
+		case *model.MedicalInsurance:
+			s.insurance[row.ID] = *row
+		case *model.Appointment:
+			s.appointments[row.ID] = *row
+		case *model.ServiceAccount:
+			s.accounts[row.ID] = *row
+		case *model.APIKey:
+			s.apiKeys[row.ID] = cloneAPIKey(row)
+		case *model.WebhookSubscription:
+			s.webhooks[row.ID] = cloneSubscription(row)
+		case *model.WebhookDelivery:
+			s.deliveries[row.ID] = *row
+		default:
+			return fmt.Errorf("memory store can not seed %T", row)
+		}
+	}
+	return nil
+}
+
+type snapshot struct {
+	users        map[uuid.UUID]model.User
+	passwords    map[uuid.UUID]model.Password
+	tokens       map[uuid.UUID]model.RefreshToken
+	addresses    map[uuid.UUID]model.Address
+	insurance    map[uuid.UUID]model.MedicalInsurance
+	appointments map[uuid.UUID]model.Appointment
+	audit        []model.AuditEvent
+	accounts     map[uuid.UUID]model.ServiceAccount
+	apiKeys      map[uuid.UUID]model.APIKey
+	webhooks     map[uuid.UUID]model.WebhookSubscription
+	deliveries   map[uuid.UUID]model.WebhookDelivery
+}
+
+func (s *Store) snapshot() snapshot {
+	return snapshot{
+		users:        maps.Clone(s.users),
+		passwords:    maps.Clone(s.passwords),
+		tokens:       maps.Clone(s.tokens),
+		addresses:    maps.Clone(s.addresses),
+		insurance:    maps.Clone(s.insurance),
+		appointments: maps.Clone(s.appointments),
+		audit:        slices.Clone(s.audit),
+		accounts:     maps.Clone(s.accounts),
+		apiKeys:      maps.Clone(s.apiKeys),
+		webhooks:     maps.Clone(s.webhooks),
+		deliveries:   maps.Clone(s.deliveries),
+	}
+}
+
+func (s *Store) restore(snapshot snapshot) {
+	s.users = snapshot.users
+	s.passwords = snapshot.passwords
+	s.tokens = snapshot.tokens
+	s.addresses = snapshot.addresses
+	s.insurance = snapshot.insurance
+	s.appointments = snapshot.appointments
+	s.audit = snapshot.audit
+	s.accounts = snapshot.accounts
+	s.apiKeys = snapshot.apiKeys
+	s.webhooks = snapshot.webhooks
+	s.deliveries = snapshot.deliveries
+}
+
+// cloneUser drops the password relation, the postgres repository doesn't
+// load it either.
+func cloneUser(user *model.User) model.User {
+	clone := *user
+	clone.Password = nil
+	return clone
+}
@@ -0,0 +1,97 @@
+package memoryRepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type RefreshTokenRepo struct {
+	store *Store
+}
+
+func NewTokenRepository(store *Store) *RefreshTokenRepo {
+	return &RefreshTokenRepo{
+		store: store,
+	}
+}
+
+func (t *RefreshTokenRepo) Save(ctx context.Context, token *model.RefreshToken) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	if _, ok := t.store.tokens[token.ID]; ok {
+		return ErrDuplicateKey
+	}
+	t.store.tokens[token.ID] = *token
+	return nil
+}
+
+func (t *RefreshTokenRepo) Delete(ctx context.Context, tokenID uuid.UUID) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	delete(t.store.tokens, tokenID)
+	return nil
+}
+
+// GetByUserID returns nil without error when the user has no token, like the
+// postgres repository.
+func (t *RefreshTokenRepo) GetByUserID(ctx context.Context, userID uuid.UUID) (*model.RefreshToken, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	for _, token := range t.store.tokens {
+		if token.UserID == userID {
+			return &token, nil
+		}
+	}
+	return nil, nil
+}
+
+func (t *RefreshTokenRepo) GetByToken(ctx context.Context, value string) (*model.RefreshToken, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	for _, token := range t.store.tokens {
+		if token.Token == value {
+			return &token, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (t *RefreshTokenRepo) DeleteByToken(ctx context.Context, value string) error {
+	return t.deleteWhere(func(token model.RefreshToken) bool {
+		return token.Token == value
+	})
+}
+
+func (t *RefreshTokenRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return t.deleteWhere(func(token model.RefreshToken) bool {
+		return token.UserID == userID
+	})
+}
+
+func (t *RefreshTokenRepo) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	deleted := 0
+	for id, token := range t.store.tokens {
+		if token.ExpiredAt.Before(before) {
+			delete(t.store.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (t *RefreshTokenRepo) deleteWhere(match func(model.RefreshToken) bool) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	for id, token := range t.store.tokens {
+		if match(token) {
+			delete(t.store.tokens, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package memoryRepository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type UserRepo struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) *UserRepo {
+	return &UserRepo{
+		store: store,
+	}
+}
+
+func (u *UserRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return u.store.WithinTransaction(ctx, fn)
+}
+
+func (u *UserRepo) Save(ctx context.Context, user *model.User) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	if _, ok := u.store.users[user.ID]; ok {
+		return ErrDuplicateKey
+	}
+	for _, existing := range u.store.users {
+		if existing.Email == user.Email {
+			return ErrDuplicateKey
+		}
+	}
+	u.store.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+func (u *UserRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	user, ok := u.store.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (u *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	for _, user := range u.store.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (u *UserRepo) Update(ctx context.Context, user *model.User) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	user.UpdateAt = time.Now()
+	if _, ok := u.store.users[user.ID]; ok {
+		u.store.users[user.ID] = cloneUser(user)
+	}
+	return nil
+}
+
+func (u *UserRepo) GetPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*model.User, error) {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	var users []*model.User
+	for _, user := range u.store.users {
+		if user.DeletionRequestedAt.Valid && !user.DeletionRequestedAt.Time.After(requestedBefore) && !user.AnonymizedAt.Valid {
+			users = append(users, &user)
+		}
+	}
+	return users, nil
+}
+
+func (u *UserRepo) ListActivePatients(ctx context.Context, limit, offset int) ([]*model.User, int, error) {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	var patients []*model.User
+	for _, user := range u.store.users {
+		if user.Rol == model.PatientRole && user.IsActive && !user.DeceasedAt.Valid && !user.AnonymizedAt.Valid {
+			patients = append(patients, &user)
+		}
+	}
+	slices.SortFunc(patients, func(a, b *model.User) int {
+		return cmp.Or(cmp.Compare(a.LastName, b.LastName), cmp.Compare(a.FirstName, b.FirstName))
+	})
+	return page(patients, limit, offset), len(patients), nil
+}
+
+// page applies limit and offset like the sql queries, a limit of 0 means no
+// limit.
+func page[T any](rows []T, limit, offset int) []T {
+	if offset >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,177 @@
+package memoryRepository
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+type WebhookSubscriptionRepo struct {
+	store *Store
+}
+
+func NewWebhookSubscriptionRepository(store *Store) *WebhookSubscriptionRepo {
+	return &WebhookSubscriptionRepo{
+		store: store,
+	}
+}
+
+func (w *WebhookSubscriptionRepo) Save(ctx context.Context, subscription *model.WebhookSubscription) error {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	if _, ok := w.store.webhooks[subscription.ID]; ok {
+		return ErrDuplicateKey
+	}
+	w.store.webhooks[subscription.ID] = cloneSubscription(subscription)
+	return nil
+}
+
+func (w *WebhookSubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.WebhookSubscription, error) {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	subscription, ok := w.store.webhooks[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	subscription = cloneSubscription(&subscription)
+	return &subscription, nil
+}
+
+func (w *WebhookSubscriptionRepo) List(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	return w.listWhere(func(model.WebhookSubscription) bool { return true }), nil
+}
+
+func (w *WebhookSubscriptionRepo) GetByEvent(ctx context.Context, event model.WebhookEvent) ([]*model.WebhookSubscription, error) {
+	return w.listWhere(func(subscription model.WebhookSubscription) bool {
+		return subscription.Subscribed(event)
+	}), nil
+}
+
+// Delete removes the deliveries of the subscription too, like the foreign
+// key of the postgres table.
+func (w *WebhookSubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	delete(w.store.webhooks, id)
+	for deliveryID, delivery := range w.store.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(w.store.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (w *WebhookSubscriptionRepo) listWhere(match func(model.WebhookSubscription) bool) []*model.WebhookSubscription {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	var subscriptions []*model.WebhookSubscription
+	for _, subscription := range w.store.webhooks {
+		if match(subscription) {
+			subscription = cloneSubscription(&subscription)
+			subscriptions = append(subscriptions, &subscription)
+		}
+	}
+	slices.SortFunc(subscriptions, func(a, b *model.WebhookSubscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return subscriptions
+}
+
+type WebhookDeliveryRepo struct {
+	store *Store
+}
+
+func NewWebhookDeliveryRepository(store *Store) *WebhookDeliveryRepo {
+	return &WebhookDeliveryRepo{
+		store: store,
+	}
+}
+
+func (w *WebhookDeliveryRepo) Save(ctx context.Context, deliveries ...*model.WebhookDelivery) error {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	for _, delivery := range deliveries {
+		if _, ok := w.store.deliveries[delivery.ID]; ok {
+			return ErrDuplicateKey
+		}
+	}
+	for _, delivery := range deliveries {
+		w.store.deliveries[delivery.ID] = *delivery
+	}
+	return nil
+}
+
+func (w *WebhookDeliveryRepo) Update(ctx context.Context, delivery *model.WebhookDelivery) error {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	if _, ok := w.store.deliveries[delivery.ID]; ok {
+		w.store.deliveries[delivery.ID] = *delivery
+	}
+	return nil
+}
+
+func (w *WebhookDeliveryRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	delivery, ok := w.store.deliveries[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &delivery, nil
+}
+
+func (w *WebhookDeliveryRepo) List(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, int, error) {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	var deliveries []*model.WebhookDelivery
+	for _, delivery := range w.store.deliveries {
+		if filter.SubscriptionID.Valid && delivery.SubscriptionID != filter.SubscriptionID.UUID {
+			continue
+		}
+		if filter.Event != "" && string(delivery.Event) != filter.Event {
+			continue
+		}
+		if filter.Status != "" && string(delivery.Status) != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	slices.SortFunc(deliveries, func(a, b *model.WebhookDelivery) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return page(deliveries, filter.Limit, filter.Offset), len(deliveries), nil
+}
+
+func (w *WebhookDeliveryRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	var due []*model.WebhookDelivery
+	for _, delivery := range w.store.deliveries {
+		if delivery.Status == model.WebhookPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, &delivery)
+		}
+	}
+	slices.SortFunc(due, func(a, b *model.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, delivery := range due {
+		delivery.NextAttemptAt = now.Add(lease)
+		w.store.deliveries[delivery.ID] = *delivery
+	}
+	return due, nil
+}
+
+// cloneSubscription copies the events so the callers can't modify the stored
+// subscription.
+func cloneSubscription(subscription *model.WebhookSubscription) model.WebhookSubscription {
+	clone := *subscription
+	clone.Events = slices.Clone(subscription.Events)
+	return clone
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/domain/model"
 	"github.com/oaxacos/vitacare/internal/infrastructure/db"
-	"github.com/uptrace/bun"
 )
 
 type PasswordRepo struct {
@@ -19,8 +18,8 @@ func NewPasswordRepository(db *db.DBRepository) *PasswordRepo {
 	}
 }
 
-func (p *PasswordRepo) Save(ctx context.Context, tx *bun.Tx, password *model.Password) error {
-	q := tx.NewInsert().Model(password)
+func (p *PasswordRepo) Save(ctx context.Context, password *model.Password) error {
+	q := p.DB.Querier(ctx).NewInsert().Model(password)
 	_, err := q.Exec(ctx)
 	return err
 }
@@ -28,7 +27,7 @@ func (p *PasswordRepo) Save(ctx context.Context, tx *bun.Tx, password *model.Pas
 func (p *PasswordRepo) getByUserID(ctx context.Context, userID uuid.UUID) (*model.Password, error) {
 	password := new(model.Password)
 
-	q := p.DB.NewSelect().Model(password).Where("user_id = ?", userID)
+	q := p.DB.Querier(ctx).NewSelect().Model(password).Where("user_id = ?", userID)
 	err := q.Scan(ctx)
 	if err != nil {
 		return nil, err
@@ -46,3 +45,8 @@ func (p *PasswordRepo) VerifyPasswordText(ctx context.Context, userId uuid.UUID,
 	}
 	return password.VerifyPassword(plainText, password.Hash)
 }
+
+func (p *PasswordRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	_, err := p.DB.Querier(ctx).NewDelete().Model((*model.Password)(nil)).Where("user_id = ?", userID).Exec(ctx)
+	return err
+}
@@ -2,28 +2,100 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/domain/model"
-	"github.com/uptrace/bun"
 )
 
+// ErrDuplicateKey is returned by the repositories that map the violations of
+// a unique constraint, so the services don't need a racy check first.
+var ErrDuplicateKey = errors.New("duplicate key value violates unique constraint")
+
 type RefreshTokenRepository interface {
 	Save(ctx context.Context, token *model.RefreshToken) error
 	Delete(ctx context.Context, tokenID uuid.UUID) error
 	GetByUserID(ctx context.Context, userID uuid.UUID) (*model.RefreshToken, error)
 	GetByToken(ctx context.Context, token string) (*model.RefreshToken, error)
 	DeleteByToken(ctx context.Context, token string) error
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// Transactor runs fn in a transaction carried by its ctx, every repository
+// method called with that ctx takes part in it.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type UserRepository interface {
-	Save(ctx context.Context, tx *bun.Tx, user *model.User) error
+	Transactor
+	Save(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
-	WithTransaction(ctx context.Context, fn func(tx *bun.Tx) error) error
-	Update(user *model.User) error
+	Update(ctx context.Context, user *model.User) error
+	GetPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*model.User, error)
+	ListActivePatients(ctx context.Context, limit, offset int) ([]*model.User, int, error)
 }
 
 type PasswordRepository interface {
 	VerifyPasswordText(ctx context.Context, userId uuid.UUID, plainText string) error
-	Save(ctx context.Context, tx *bun.Tx, password *model.Password) error
+	Save(ctx context.Context, password *model.Password) error
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+type AddressRepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Address, error)
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+type InsuranceRepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.MedicalInsurance, error)
+}
+
+type AppointmentRepository interface {
+	GetByPatientID(ctx context.Context, patientID uuid.UUID) ([]*model.Appointment, error)
+	// CancelFutureByPatientID returns the cancelled appointments.
+	CancelFutureByPatientID(ctx context.Context, patientID uuid.UUID, reason string) ([]*model.Appointment, error)
+}
+
+type AuditRepository interface {
+	Append(ctx context.Context, event *model.AuditEvent) error
+	List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, int, error)
+	GetChain(ctx context.Context, afterSeq int64, limit int) ([]*model.AuditEvent, error)
+}
+
+type ServiceAccountRepository interface {
+	Save(ctx context.Context, account *model.ServiceAccount) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.ServiceAccount, error)
+	List(ctx context.Context) ([]*model.ServiceAccount, error)
+}
+
+type APIKeyRepository interface {
+	Transactor
+	Save(ctx context.Context, key *model.APIKey) error
+	Update(ctx context.Context, key *model.APIKey) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	GetByServiceAccountID(ctx context.Context, serviceAccountID uuid.UUID) ([]*model.APIKey, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type WebhookSubscriptionRepository interface {
+	Save(ctx context.Context, subscription *model.WebhookSubscription) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.WebhookSubscription, error)
+	List(ctx context.Context) ([]*model.WebhookSubscription, error)
+	GetByEvent(ctx context.Context, event model.WebhookEvent) ([]*model.WebhookSubscription, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type WebhookDeliveryRepository interface {
+	Save(ctx context.Context, deliveries ...*model.WebhookDelivery) error
+	Update(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error)
+	List(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, int, error)
+	// ClaimDue returns the pending deliveries due at now and postpones them
+	// by lease, so the other instances don't send them at the same time.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error)
 }
@@ -0,0 +1,479 @@
+// Package repositoryTest holds the contract every implementation of the
+// repository interfaces has to fulfil, the postgres and in-memory
+// repositories run the same suite so the fakes can't drift from the real
+// behaviour.
+package repositoryTest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errRollback = errors.New("rollback")
+
+// Repositories is the set of repositories under test, they must share the
+// same storage so transactions span all of them.
+type Repositories struct {
+	User         repository.UserRepository
+	Password     repository.PasswordRepository
+	Token        repository.RefreshTokenRepository
+	Address      repository.AddressRepository
+	Insurance    repository.InsuranceRepository
+	Appointments repository.AppointmentRepository
+	Audit        repository.AuditRepository
+	Accounts     repository.ServiceAccountRepository
+	APIKeys      repository.APIKeyRepository
+	Webhooks     repository.WebhookSubscriptionRepository
+	Deliveries   repository.WebhookDeliveryRepository
+	// Seed inserts rows of the repositories without an insert method.
+	Seed func(ctx context.Context, rows ...any) error
+}
+
+// Factory returns empty repositories for every subtest.
+type Factory func(t *testing.T) Repositories
+
+func Run(t *testing.T, newRepositories Factory) {
+	t.Run("users", func(t *testing.T) { testUsers(t, newRepositories(t)) })
+	t.Run("transactions", func(t *testing.T) { testTransactions(t, newRepositories(t)) })
+	t.Run("passwords", func(t *testing.T) { testPasswords(t, newRepositories(t)) })
+	t.Run("tokens", func(t *testing.T) { testTokens(t, newRepositories(t)) })
+	t.Run("addresses", func(t *testing.T) { testAddresses(t, newRepositories(t)) })
+	t.Run("insurance", func(t *testing.T) { testInsurance(t, newRepositories(t)) })
+	t.Run("appointments", func(t *testing.T) { testAppointments(t, newRepositories(t)) })
+	t.Run("audit", func(t *testing.T) { testAudit(t, newRepositories(t)) })
+	t.Run("api keys", func(t *testing.T) { testAPIKeys(t, newRepositories(t)) })
+	t.Run("webhooks", func(t *testing.T) { testWebhooks(t, newRepositories(t)) })
+}
+
+func newUser(email, firstName, lastName string, role model.UserRole) *model.User {
+	user := model.NewUser(dto.UserDto{
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+	}, role)
+	user.Password = nil
+	return user
+}
+
+func testUsers(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	ana := newUser("ana@test.com", "Ana", "Lopez", model.PatientRole)
+	require.NoError(t, repos.User.Save(ctx, ana))
+
+	got, err := repos.User.GetByID(ctx, ana.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ana.Email, got.Email)
+	assert.Equal(t, model.PatientRole, got.Rol)
+	assert.Equal(t, ana.Language, got.Language)
+
+	got, err = repos.User.GetByEmail(ctx, "ana@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, ana.ID, got.ID)
+
+	_, err = repos.User.GetByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = repos.User.GetByEmail(ctx, "nobody@test.com")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = repos.User.Save(ctx, newUser("ana@test.com", "Other", "Ana", model.PatientRole))
+	assert.Error(t, err, "the email is unique")
+
+	got.Phone = "5550100"
+	require.NoError(t, repos.User.Update(ctx, got))
+	got, err = repos.User.GetByID(ctx, ana.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "5550100", got.Phone)
+
+	bruno := newUser("bruno@test.com", "Bruno", "Diaz", model.PatientRole)
+	deceased := newUser("carla@test.com", "Carla", "Mora", model.PatientRole)
+	require.NoError(t, deceased.MarkDeceased(time.Now().Add(-time.Hour)))
+	doctor := newUser("doctor@test.com", "Dario", "Cruz", model.DoctorRole)
+	for _, user := range []*model.User{bruno, deceased, doctor} {
+		require.NoError(t, repos.User.Save(ctx, user))
+	}
+
+	patients, total, err := repos.User.ListActivePatients(ctx, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, patients, 1)
+	assert.Equal(t, bruno.ID, patients[0].ID, "patients are sorted by last name")
+	patients, _, err = repos.User.ListActivePatients(ctx, 10, 1)
+	require.NoError(t, err)
+	require.Len(t, patients, 1)
+	assert.Equal(t, ana.ID, patients[0].ID)
+
+	require.NoError(t, bruno.RequestDeletion())
+	require.NoError(t, repos.User.Update(ctx, bruno))
+	pending, err := repos.User.GetPendingDeletion(ctx, time.Now())
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, bruno.ID, pending[0].ID)
+	pending, err = repos.User.GetPendingDeletion(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func testTransactions(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	committed := newUser("commit@test.com", "Commit", "Ted", model.PatientRole)
+	err := repos.User.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := repos.User.Save(ctx, committed)
+		if err != nil {
+			return err
+		}
+		return repos.Password.Save(ctx, newPassword(t, committed.ID, "secret"))
+	})
+	require.NoError(t, err)
+	_, err = repos.User.GetByID(ctx, committed.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, repos.Password.VerifyPasswordText(ctx, committed.ID, "secret"))
+
+	rolledBack := newUser("rollback@test.com", "Roll", "Back", model.PatientRole)
+	err = repos.User.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := repos.User.Save(ctx, rolledBack)
+		if err != nil {
+			return err
+		}
+		err = repos.Password.Save(ctx, newPassword(t, rolledBack.ID, "secret"))
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	_, err = repos.User.GetByID(ctx, rolledBack.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, repos.Password.VerifyPasswordText(ctx, rolledBack.ID, "secret"), sql.ErrNoRows)
+
+	outer := newUser("outer@test.com", "Outer", "Tx", model.PatientRole)
+	inner := newUser("inner@test.com", "Inner", "Tx", model.PatientRole)
+	err = repos.User.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := repos.User.Save(ctx, outer)
+		if err != nil {
+			return err
+		}
+		err = repos.User.WithinTransaction(ctx, func(ctx context.Context) error {
+			err := repos.User.Save(ctx, inner)
+			if err != nil {
+				return err
+			}
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = repos.User.GetByID(ctx, outer.ID)
+	assert.NoError(t, err, "the outer transaction commits")
+	_, err = repos.User.GetByID(ctx, inner.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "the savepoint is rolled back")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = repos.User.WithinTransaction(cancelled, func(ctx context.Context) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func newPassword(t *testing.T, userID uuid.UUID, plainText string) *model.Password {
+	password := model.NewPassword(userID, plainText)
+	require.NoError(t, password.SetHash())
+	return password
+}
+
+func testPasswords(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser("password@test.com", "Pass", "Word", model.PatientRole)
+	require.NoError(t, repos.User.Save(ctx, user))
+
+	assert.ErrorIs(t, repos.Password.VerifyPasswordText(ctx, user.ID, "secret"), sql.ErrNoRows)
+
+	require.NoError(t, repos.Password.Save(ctx, newPassword(t, user.ID, "secret")))
+	assert.NoError(t, repos.Password.VerifyPasswordText(ctx, user.ID, "secret"))
+	assert.ErrorIs(t, repos.Password.VerifyPasswordText(ctx, user.ID, "wrong"), model.ErrorPasswordIncorrect)
+
+	require.NoError(t, repos.Password.DeleteByUserID(ctx, user.ID))
+	assert.ErrorIs(t, repos.Password.VerifyPasswordText(ctx, user.ID, "secret"), sql.ErrNoRows)
+}
+
+func testTokens(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser("token@test.com", "To", "Ken", model.PatientRole)
+	require.NoError(t, repos.User.Save(ctx, user))
+
+	token, err := repos.Token.GetByUserID(ctx, user.ID)
+	require.NoError(t, err, "a user without session is not an error")
+	assert.Nil(t, token)
+	_, err = repos.Token.GetByToken(ctx, "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	active := model.NewRefreshToken("active", user.ID, time.Hour)
+	require.NoError(t, repos.Token.Save(ctx, active))
+	token, err = repos.Token.GetByToken(ctx, "active")
+	require.NoError(t, err)
+	assert.Equal(t, active.ID, token.ID)
+	token, err = repos.Token.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "active", token.Token)
+
+	require.NoError(t, repos.Token.Save(ctx, model.NewRefreshToken("expired", user.ID, -time.Hour)))
+	deleted, err := repos.Token.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, err = repos.Token.GetByToken(ctx, "expired")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, repos.Token.DeleteByToken(ctx, "active"))
+	_, err = repos.Token.GetByToken(ctx, "active")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, repos.Token.Save(ctx, model.NewRefreshToken("other", user.ID, time.Hour)))
+	require.NoError(t, repos.Token.DeleteByUserID(ctx, user.ID))
+	token, err = repos.Token.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, token)
+}
+
+func testAddresses(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser("address@test.com", "Add", "Ress", model.PatientRole)
+	require.NoError(t, repos.User.Save(ctx, user))
+	require.NoError(t, repos.Seed(ctx, &model.Address{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		AddressLine1: "Calle 5 de Mayo 10",
+		ZipCode:      "68000",
+		Country:      "MX",
+		City:         "Oaxaca",
+		State:        "Oaxaca",
+	}))
+
+	addresses, err := repos.Address.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, addresses, 1)
+	assert.Equal(t, "Oaxaca", addresses[0].City)
+
+	require.NoError(t, repos.Address.DeleteByUserID(ctx, user.ID))
+	addresses, err = repos.Address.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, addresses)
+}
+
+func testInsurance(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser("insurance@test.com", "In", "Surance", model.PatientRole)
+	other := newUser("other-insurance@test.com", "Ot", "Her", model.PatientRole)
+	require.NoError(t, repos.User.Save(ctx, user))
+	require.NoError(t, repos.User.Save(ctx, other))
+	require.NoError(t, repos.Seed(ctx,
+		&model.MedicalInsurance{ID: uuid.New(), UserID: user.ID, Name: "Seguro Popular", Institution: "IMSS", SocialSecurityNumber: "12345678901"},
+		&model.MedicalInsurance{ID: uuid.New(), UserID: other.ID, Name: "Privado", Institution: "GNP", SocialSecurityNumber: "10987654321"},
+	))
+
+	insurance, err := repos.Insurance.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, insurance, 1)
+	assert.Equal(t, "IMSS", insurance[0].Institution)
+	assert.Equal(t, "12345678901", insurance[0].SocialSecurityNumber)
+
+	insurance, err = repos.Insurance.GetByUserID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, insurance)
+}
+
+func testAppointments(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	patient := newUser("appointments@test.com", "Ap", "Pointment", model.PatientRole)
+	doctor := newUser("doctor-appointments@test.com", "Doc", "Tor", model.DoctorRole)
+	require.NoError(t, repos.User.Save(ctx, patient))
+	require.NoError(t, repos.User.Save(ctx, doctor))
+
+	now := time.Now().Truncate(time.Second)
+	appointment := func(date time.Time, status model.AppointmentStatus) *model.Appointment {
+		return &model.Appointment{
+			ID:        uuid.New(),
+			Date:      date,
+			PatientID: patient.ID,
+			DoctorID:  doctor.ID,
+			CreatedAt: now,
+			UpdateAt:  now,
+			Status:    status,
+		}
+	}
+	past := appointment(now.AddDate(0, 0, -7), model.AppointmentScheduled)
+	tomorrow := appointment(now.AddDate(0, 0, 1), model.AppointmentScheduled)
+	nextWeek := appointment(now.AddDate(0, 0, 7), model.AppointmentScheduled)
+	cancelled := appointment(now.AddDate(0, 0, 3), model.AppointmentCancelled)
+	require.NoError(t, repos.Seed(ctx, past, tomorrow, nextWeek, cancelled))
+
+	appointments, err := repos.Appointments.GetByPatientID(ctx, patient.ID)
+	require.NoError(t, err)
+	require.Len(t, appointments, 4)
+	assert.Equal(t, nextWeek.ID, appointments[0].ID, "the latest appointment comes first")
+	assert.Equal(t, past.ID, appointments[3].ID)
+
+	cancelledNow, err := repos.Appointments.CancelFutureByPatientID(ctx, uuid.New(), "deceased")
+	require.NoError(t, err)
+	assert.Empty(t, cancelledNow)
+
+	cancelledNow, err = repos.Appointments.CancelFutureByPatientID(ctx, patient.ID, "deceased")
+	require.NoError(t, err)
+	require.Len(t, cancelledNow, 2, "only the future scheduled appointments are cancelled")
+	assert.ElementsMatch(t, []uuid.UUID{tomorrow.ID, nextWeek.ID}, []uuid.UUID{cancelledNow[0].ID, cancelledNow[1].ID})
+	assert.Equal(t, model.AppointmentCancelled, cancelledNow[0].Status)
+
+	appointments, err = repos.Appointments.GetByPatientID(ctx, patient.ID)
+	require.NoError(t, err)
+	byID := make(map[uuid.UUID]*model.Appointment, len(appointments))
+	for _, appointment := range appointments {
+		byID[appointment.ID] = appointment
+	}
+	for _, id := range []uuid.UUID{tomorrow.ID, nextWeek.ID} {
+		assert.Equal(t, model.AppointmentCancelled, byID[id].Status)
+		assert.True(t, byID[id].CancelledAt.Valid)
+		assert.Equal(t, sql.NullString{String: "deceased", Valid: true}, byID[id].CancellationReason)
+	}
+	assert.Equal(t, model.AppointmentScheduled, byID[past.ID].Status, "the past appointments are kept")
+	assert.False(t, byID[cancelled.ID].CancellationReason.Valid, "the cancelled appointments are not touched")
+
+	cancelledNow, err = repos.Appointments.CancelFutureByPatientID(ctx, patient.ID, "deceased")
+	require.NoError(t, err)
+	assert.Empty(t, cancelledNow)
+}
+
+func testAudit(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	actor, target := uuid.New(), uuid.New()
+	actions := []model.AuditAction{model.AuditLogin, model.AuditRecordAccessed, model.AuditLogin}
+	for _, action := range actions {
+		require.NoError(t, repos.Audit.Append(ctx, model.NewAuditEvent(action, actor, target)))
+	}
+
+	chain, err := repos.Audit.GetChain(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, chain, len(actions))
+	prevHash := ""
+	for i, event := range chain {
+		assert.Equal(t, actions[i], event.Action)
+		assert.True(t, event.Verify(prevHash), "event %d breaks the chain", i)
+		prevHash = event.Hash
+	}
+	rest, err := repos.Audit.GetChain(ctx, chain[0].Seq, 1)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, chain[1].ID, rest[0].ID)
+
+	events, total, err := repos.Audit.List(ctx, model.AuditFilter{Action: string(model.AuditLogin), Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, events, 1)
+	assert.Equal(t, chain[2].ID, events[0].ID, "the newest event comes first")
+
+	err = repos.User.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := repos.Audit.Append(ctx, model.NewAuditEvent(model.AuditRecordAccessed, actor, target))
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	_, total, err = repos.Audit.List(ctx, model.AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, len(actions), total, "the event is rolled back with the transaction")
+}
+
+func testAPIKeys(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	account := model.NewServiceAccount("lab", "results import", uuid.New())
+	require.NoError(t, repos.Accounts.Save(ctx, account))
+	assert.ErrorIs(t, repos.Accounts.Save(ctx, model.NewServiceAccount("lab", "", uuid.New())), repository.ErrDuplicateKey, "the name is unique")
+
+	got, err := repos.Accounts.GetByID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "lab", got.Name)
+	_, err = repos.Accounts.GetByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	accounts, err := repos.Accounts.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, accounts, 1)
+
+	key, _, err := model.NewAPIKey(account.ID, "main", "abc123", "secret", []model.Scope{model.ScopePatientsRead}, 60)
+	require.NoError(t, err)
+	require.NoError(t, repos.APIKeys.Save(ctx, key))
+
+	got2, err := repos.APIKeys.GetByPrefix(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got2.ID)
+	assert.Equal(t, []model.Scope{model.ScopePatientsRead}, got2.Scopes)
+	assert.True(t, got2.Verify("secret"))
+	_, err = repos.APIKeys.GetByPrefix(ctx, "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, repos.APIKeys.TouchLastUsed(ctx, key.ID, time.Now()))
+	require.NoError(t, key.Revoke(time.Now()))
+	require.NoError(t, repos.APIKeys.Update(ctx, key))
+	got2, err = repos.APIKeys.GetByID(ctx, key.ID)
+	require.NoError(t, err)
+	assert.True(t, got2.RevokedAt.Valid)
+	assert.True(t, got2.LastUsedAt.Valid)
+
+	keys, err := repos.APIKeys.GetByServiceAccountID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func testWebhooks(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	subscription, err := model.NewWebhookSubscription("https://lab.test/hooks", "", "secret",
+		[]model.WebhookEvent{model.WebhookPatientRegistered}, uuid.New())
+	require.NoError(t, err)
+	require.NoError(t, repos.Webhooks.Save(ctx, subscription))
+
+	subscriptions, err := repos.Webhooks.GetByEvent(ctx, model.WebhookPatientRegistered)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, subscription.Events, subscriptions[0].Events)
+	subscriptions, err = repos.Webhooks.GetByEvent(ctx, model.WebhookAppointmentCancelled)
+	require.NoError(t, err)
+	assert.Empty(t, subscriptions)
+
+	payload := model.WebhookPayload{ID: uuid.New(), Event: model.WebhookPatientRegistered}
+	due := model.NewWebhookDelivery(subscription.ID, payload, []byte(`{"id":1}`))
+	later := model.NewWebhookDelivery(subscription.ID, payload, []byte(`{"id":2}`))
+	later.NextAttemptAt = time.Now().Add(time.Hour)
+	require.NoError(t, repos.Deliveries.Save(ctx, due, later))
+
+	now := time.Now()
+	claimed, err := repos.Deliveries.ClaimDue(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, due.ID, claimed[0].ID)
+	assert.JSONEq(t, `{"id":1}`, string(claimed[0].Payload))
+	claimed, err = repos.Deliveries.ClaimDue(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "a claimed delivery is not due until its lease expires")
+
+	due.Succeed(now, 200)
+	require.NoError(t, repos.Deliveries.Update(ctx, due))
+	deliveries, total, err := repos.Deliveries.List(ctx, model.WebhookDeliveryFilter{Status: string(model.WebhookDelivered), Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 200, deliveries[0].ResponseStatus)
+
+	require.NoError(t, repos.Webhooks.Delete(ctx, subscription.ID))
+	_, err = repos.Deliveries.GetByID(ctx, later.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "the deliveries are deleted with the subscription")
+}
@@ -0,0 +1,61 @@
+package repositoryTest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oaxacos/vitacare/internal/config"
+	addressRepository "github.com/oaxacos/vitacare/internal/domain/repository/address"
+	auditRepository "github.com/oaxacos/vitacare/internal/domain/repository/audit"
+	"github.com/oaxacos/vitacare/internal/domain/repository/password"
+	serviceAccountRepository "github.com/oaxacos/vitacare/internal/domain/repository/serviceaccount"
+	tokenRepository "github.com/oaxacos/vitacare/internal/domain/repository/token"
+	userRepository "github.com/oaxacos/vitacare/internal/domain/repository/user"
+	webhookRepository "github.com/oaxacos/vitacare/internal/domain/repository/webhook"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+	"github.com/oaxacos/vitacare/pkg/utils"
+)
+
+var tables = []string{"users", "user_passwords", "tokens", "address", "audit_events", "api_keys", "service_accounts", "webhook_deliveries", "webhook_subscriptions"}
+
+// TestPostgresRepositories runs the contract against the test database, it
+// is skipped when the database is not reachable.
+func TestPostgresRepositories(t *testing.T) {
+	conf, err := config.NewConfig("test")
+	if err != nil {
+		t.Fatalf("error loading config %v", err)
+	}
+	repoDb, err := db.NewConnection(conf)
+	if err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+	defer repoDb.Close()
+
+	Run(t, func(t *testing.T) Repositories {
+		ctx := context.Background()
+		err := utils.CleanUpDB(repoDb.DB, ctx, tables)
+		if err != nil {
+			t.Fatalf("error cleaning up db %v", err)
+		}
+		return Repositories{
+			User:       userRepository.NewUserRepository(repoDb),
+			Password:   password.NewPasswordRepository(repoDb),
+			Token:      tokenRepository.NewTokenRepository(repoDb),
+			Address:    addressRepository.NewAddressRepository(repoDb),
+			Audit:      auditRepository.NewAuditRepository(repoDb),
+			Accounts:   serviceAccountRepository.NewServiceAccountRepository(repoDb),
+			APIKeys:    serviceAccountRepository.NewAPIKeyRepository(repoDb),
+			Webhooks:   webhookRepository.NewSubscriptionRepository(repoDb),
+			Deliveries: webhookRepository.NewDeliveryRepository(repoDb),
+			Seed: func(ctx context.Context, rows ...any) error {
+				for _, row := range rows {
+					_, err := repoDb.Querier(ctx).NewInsert().Model(row).Exec(ctx)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+	})
+}
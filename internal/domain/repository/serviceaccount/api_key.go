@@ -0,0 +1,75 @@
+package serviceAccountRepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+type APIKeyRepo struct {
+	DB *db.DBRepository
+}
+
+func NewAPIKeyRepository(db *db.DBRepository) *APIKeyRepo {
+	return &APIKeyRepo{
+		DB: db,
+	}
+}
+
+func (a *APIKeyRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return a.DB.WithinTransaction(ctx, fn)
+}
+
+func (a *APIKeyRepo) Save(ctx context.Context, key *model.APIKey) error {
+	_, err := a.DB.Querier(ctx).NewInsert().Model(key).Exec(ctx)
+	return err
+}
+
+// Update leaves last_used_at alone, it is only written by TouchLastUsed.
+func (a *APIKeyRepo) Update(ctx context.Context, key *model.APIKey) error {
+	_, err := a.DB.Querier(ctx).NewUpdate().Model(key).ExcludeColumn("last_used_at").WherePK().Exec(ctx)
+	return err
+}
+
+func (a *APIKeyRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	key := new(model.APIKey)
+	err := a.DB.Querier(ctx).NewSelect().Model(key).Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (a *APIKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	key := new(model.APIKey)
+	err := a.DB.Querier(ctx).NewSelect().Model(key).Where("prefix = ?", prefix).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (a *APIKeyRepo) GetByServiceAccountID(ctx context.Context, serviceAccountID uuid.UUID) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	err := a.DB.Querier(ctx).NewSelect().Model(&keys).
+		Where("service_account_id = ?", serviceAccountID).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// TouchLastUsed only updates the column so it can't overwrite a concurrent
+// revocation.
+func (a *APIKeyRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := a.DB.Querier(ctx).NewUpdate().Model((*model.APIKey)(nil)).
+		Set("last_used_at = ?", at).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
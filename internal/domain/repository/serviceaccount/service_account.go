@@ -0,0 +1,47 @@
+package serviceAccountRepository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+type ServiceAccountRepo struct {
+	DB *db.DBRepository
+}
+
+func NewServiceAccountRepository(db *db.DBRepository) *ServiceAccountRepo {
+	return &ServiceAccountRepo{
+		DB: db,
+	}
+}
+
+// Save returns repository.ErrDuplicateKey when the name is taken.
+func (s *ServiceAccountRepo) Save(ctx context.Context, account *model.ServiceAccount) error {
+	_, err := s.DB.Querier(ctx).NewInsert().Model(account).Exec(ctx)
+	if db.IsUniqueViolation(err) {
+		return repository.ErrDuplicateKey
+	}
+	return err
+}
+
+func (s *ServiceAccountRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.ServiceAccount, error) {
+	account := new(model.ServiceAccount)
+	err := s.DB.Querier(ctx).NewSelect().Model(account).Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *ServiceAccountRepo) List(ctx context.Context) ([]*model.ServiceAccount, error) {
+	var accounts []*model.ServiceAccount
+	err := s.DB.Reader(ctx).NewSelect().Model(&accounts).Order("name ASC").Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
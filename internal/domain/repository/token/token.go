@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/domain/model"
 	"github.com/oaxacos/vitacare/internal/infrastructure/db"
@@ -21,18 +23,18 @@ func NewTokenRepository(db *db.DBRepository) *RefreshTokenRepo {
 }
 
 func (t *RefreshTokenRepo) Save(ctx context.Context, token *model.RefreshToken) error {
-	_, err := t.DB.NewInsert().Model(token).Exec(ctx)
+	_, err := t.DB.Querier(ctx).NewInsert().Model(token).Exec(ctx)
 	return err
 }
 
 func (t *RefreshTokenRepo) Delete(ctx context.Context, token uuid.UUID) error {
-	_, err := t.DB.NewDelete().Model((*model.RefreshToken)(nil)).Where("id = ?", token).Exec(ctx)
+	_, err := t.DB.Querier(ctx).NewDelete().Model((*model.RefreshToken)(nil)).Where("id = ?", token).Exec(ctx)
 	return err
 }
 
 func (t *RefreshTokenRepo) GetByUserID(ctx context.Context, userID uuid.UUID) (*model.RefreshToken, error) {
 	token := new(model.RefreshToken)
-	q := t.DB.NewSelect().Model(token).Where("user_id = ?", userID)
+	q := t.DB.Querier(ctx).NewSelect().Model(token).Where("user_id = ?", userID)
 	logger.GetContextLogger(ctx).Debugf("query: %s", q.String())
 	err := q.Scan(ctx)
 	if err != nil {
@@ -46,7 +48,7 @@ func (t *RefreshTokenRepo) GetByUserID(ctx context.Context, userID uuid.UUID) (*
 
 func (t *RefreshTokenRepo) GetByToken(ctx context.Context, token string) (*model.RefreshToken, error) {
 	refreshToken := new(model.RefreshToken)
-	q := t.DB.NewSelect().Model(refreshToken).Where("token = ?", token)
+	q := t.DB.Querier(ctx).NewSelect().Model(refreshToken).Where("token = ?", token)
 	err := q.Scan(ctx)
 	if err != nil {
 		return nil, err
@@ -55,6 +57,20 @@ func (t *RefreshTokenRepo) GetByToken(ctx context.Context, token string) (*model
 }
 
 func (t *RefreshTokenRepo) DeleteByToken(ctx context.Context, token string) error {
-	_, err := t.DB.NewDelete().Model((*model.RefreshToken)(nil)).Where("token = ?", token).Exec(ctx)
+	_, err := t.DB.Querier(ctx).NewDelete().Model((*model.RefreshToken)(nil)).Where("token = ?", token).Exec(ctx)
+	return err
+}
+
+func (t *RefreshTokenRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	_, err := t.DB.Querier(ctx).NewDelete().Model((*model.RefreshToken)(nil)).Where("user_id = ?", userID).Exec(ctx)
 	return err
 }
+
+func (t *RefreshTokenRepo) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	res, err := t.DB.Querier(ctx).NewDelete().Model((*model.RefreshToken)(nil)).Where("expired_at < ?", before).Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	return int(deleted), err
+}
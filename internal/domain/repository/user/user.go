@@ -6,8 +6,6 @@ import (
 	"errors"
 	"time"
 
-	"github.com/uptrace/bun"
-
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/domain/model"
 	"github.com/oaxacos/vitacare/internal/infrastructure/db"
@@ -27,8 +25,8 @@ func NewUserRepository(db *db.DBRepository) *UserRepo {
 	}
 }
 
-func (u *UserRepo) Save(ctx context.Context, tx *bun.Tx, user *model.User) error {
-	q := tx.NewInsert().Model(user)
+func (u *UserRepo) Save(ctx context.Context, user *model.User) error {
+	q := u.DB.Querier(ctx).NewInsert().Model(user)
 	_, err := q.Exec(ctx)
 	return err
 }
@@ -36,7 +34,7 @@ func (u *UserRepo) Save(ctx context.Context, tx *bun.Tx, user *model.User) error
 func (u *UserRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
 	user := new(model.User)
 
-	q := u.DB.NewSelect().Model(user).Where("id = ?", id)
+	q := u.DB.Querier(ctx).NewSelect().Model(user).Where("id = ?", id)
 	err := q.Scan(ctx)
 	if err != nil {
 		return nil, err
@@ -46,7 +44,7 @@ func (u *UserRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.User, erro
 
 func (u *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	user := new(model.User)
-	q := u.DB.NewSelect().Model(user).Where("email = ?", email)
+	q := u.DB.Querier(ctx).NewSelect().Model(user).Where("email = ?", email)
 	err := q.Scan(ctx)
 	if err != nil {
 		return nil, err
@@ -56,7 +54,7 @@ func (u *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, e
 
 func (u *UserRepo) AlreadyExist(ctx context.Context, email string) error {
 	user := new(model.User)
-	q := u.DB.NewSelect().Model(user).Where("email = ?", email)
+	q := u.DB.Querier(ctx).NewSelect().Model(user).Where("email = ?", email)
 	err := q.Scan(ctx)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -71,16 +69,47 @@ func (u *UserRepo) AlreadyExist(ctx context.Context, email string) error {
 	return nil
 }
 
-func (u *UserRepo) WithTransaction(ctx context.Context, fn func(tx *bun.Tx) error) error {
-	return u.DB.WithTransaction(ctx, fn)
+func (u *UserRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return u.DB.WithinTransaction(ctx, fn)
 }
 
-func (u *UserRepo) Update(user *model.User) error {
+func (u *UserRepo) Update(ctx context.Context, user *model.User) error {
 	user.UpdateAt = time.Now()
-	q := u.DB.NewUpdate().Model(user).WherePK()
-	_, err := q.Exec(context.Background())
+	q := u.DB.Querier(ctx).NewUpdate().Model(user).WherePK()
+	_, err := q.Exec(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (u *UserRepo) GetPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*model.User, error) {
+	var users []*model.User
+	q := u.DB.Querier(ctx).NewSelect().Model(&users).
+		Where("deletion_requested_at IS NOT NULL").
+		Where("deletion_requested_at <= ?", requestedBefore).
+		Where("anonymized_at IS NULL")
+	err := q.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ListActivePatients returns the patients that are active and alive, and the total count.
+func (u *UserRepo) ListActivePatients(ctx context.Context, limit, offset int) ([]*model.User, int, error) {
+	var users []*model.User
+	q := u.DB.Reader(ctx).NewSelect().Model(&users).
+		Where("rol = ?", model.PatientRole).
+		Where("is_active = true").
+		Where("deceased_at IS NULL").
+		Where("anonymized_at IS NULL").
+		Order("last_name ASC", "first_name ASC").
+		Limit(limit).
+		Offset(offset)
+	count, err := q.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, count, nil
+}
@@ -0,0 +1,80 @@
+package webhookRepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+type DeliveryRepo struct {
+	DB *db.DBRepository
+}
+
+func NewDeliveryRepository(db *db.DBRepository) *DeliveryRepo {
+	return &DeliveryRepo{
+		DB: db,
+	}
+}
+
+func (d *DeliveryRepo) Save(ctx context.Context, deliveries ...*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	_, err := d.DB.Querier(ctx).NewInsert().Model(&deliveries).Exec(ctx)
+	return err
+}
+
+func (d *DeliveryRepo) Update(ctx context.Context, delivery *model.WebhookDelivery) error {
+	_, err := d.DB.Querier(ctx).NewUpdate().Model(delivery).WherePK().Exec(ctx)
+	return err
+}
+
+func (d *DeliveryRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	delivery := new(model.WebhookDelivery)
+	err := d.DB.Querier(ctx).NewSelect().Model(delivery).Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (d *DeliveryRepo) List(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, int, error) {
+	var deliveries []*model.WebhookDelivery
+	q := d.DB.Reader(ctx).NewSelect().Model(&deliveries)
+	if filter.SubscriptionID.Valid {
+		q = q.Where("subscription_id = ?", filter.SubscriptionID.UUID)
+	}
+	if filter.Event != "" {
+		q = q.Where("event = ?", filter.Event)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	count, err := q.Order("created_at DESC").Limit(filter.Limit).Offset(filter.Offset).ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, count, nil
+}
+
+// ClaimDue skips the rows locked by the other instances instead of waiting
+// for them.
+func (d *DeliveryRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := d.DB.Querier(ctx).NewRaw(`UPDATE "webhook_deliveries" SET "next_attempt_at" = ?
+		WHERE "id" IN (
+			SELECT "id" FROM "webhook_deliveries"
+			WHERE "status" = ? AND "next_attempt_at" <= ?
+			ORDER BY "next_attempt_at" LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), model.WebhookPending, now, limit).
+		Scan(ctx, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
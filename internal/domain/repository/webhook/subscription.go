@@ -0,0 +1,57 @@
+package webhookRepository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+)
+
+type SubscriptionRepo struct {
+	DB *db.DBRepository
+}
+
+func NewSubscriptionRepository(db *db.DBRepository) *SubscriptionRepo {
+	return &SubscriptionRepo{
+		DB: db,
+	}
+}
+
+func (s *SubscriptionRepo) Save(ctx context.Context, subscription *model.WebhookSubscription) error {
+	_, err := s.DB.Querier(ctx).NewInsert().Model(subscription).Exec(ctx)
+	return err
+}
+
+func (s *SubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.WebhookSubscription, error) {
+	subscription := new(model.WebhookSubscription)
+	err := s.DB.Querier(ctx).NewSelect().Model(subscription).Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *SubscriptionRepo) List(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	err := s.DB.Querier(ctx).NewSelect().Model(&subscriptions).Order("created_at ASC").Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (s *SubscriptionRepo) GetByEvent(ctx context.Context, event model.WebhookEvent) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	err := s.DB.Querier(ctx).NewSelect().Model(&subscriptions).Where("? = ANY(events)", event).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Delete removes the deliveries of the subscription too.
+func (s *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := s.DB.Querier(ctx).NewDelete().Model((*model.WebhookSubscription)(nil)).Where("id = ?", id).Exec(ctx)
+	return err
+}
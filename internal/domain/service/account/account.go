@@ -0,0 +1,315 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+	"github.com/oaxacos/vitacare/internal/domain/service/audit"
+	"github.com/oaxacos/vitacare/internal/domain/service/webhook"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/tracing"
+)
+
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+var (
+	ErrNoUserWithID = apperror.NotFound("user_not_found", "user not found")
+	ErrNotAPatient  = apperror.Validation("user_not_patient", "only patients can be marked as deceased")
+)
+
+type AccountService struct {
+	UserRepo        repository.UserRepository
+	PasswordRepo    repository.PasswordRepository
+	TokenRepo       repository.RefreshTokenRepository
+	AddressRepo     repository.AddressRepository
+	InsuranceRepo   repository.InsuranceRepository
+	AppointmentRepo repository.AppointmentRepository
+	audit           audit.AuditLogger
+	webhooks        webhook.Publisher
+	gracePeriod     time.Duration
+}
+
+type Repositories struct {
+	User        repository.UserRepository
+	Password    repository.PasswordRepository
+	Token       repository.RefreshTokenRepository
+	Address     repository.AddressRepository
+	Insurance   repository.InsuranceRepository
+	Appointment repository.AppointmentRepository
+}
+
+func NewAccountService(conf *config.Config, repos Repositories, auditLogger audit.AuditLogger, webhooks webhook.Publisher) *AccountService {
+	gracePeriod := defaultDeletionGracePeriod
+	if conf.Privacy.DeletionGracePeriod > 0 {
+		gracePeriod = time.Duration(conf.Privacy.DeletionGracePeriod) * 24 * time.Hour
+	}
+	return &AccountService{
+		UserRepo:        repos.User,
+		PasswordRepo:    repos.Password,
+		TokenRepo:       repos.Token,
+		AddressRepo:     repos.Address,
+		InsuranceRepo:   repos.Insurance,
+		AppointmentRepo: repos.Appointment,
+		audit:           auditLogger,
+		webhooks:        webhooks,
+		gracePeriod:     gracePeriod,
+	}
+}
+
+func (a *AccountService) getUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	user, err := a.UserRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoUserWithID
+		}
+		return nil, err
+	}
+	if user.IsAnonymized() {
+		return nil, ErrNoUserWithID
+	}
+	return user, nil
+}
+
+// ExportUserData collects all the data we hold about the user.
+func (a *AccountService) ExportUserData(ctx context.Context, id uuid.UUID) (*model.UserDataExport, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ExportUserData")
+	defer span.End()
+	log := logger.GetContextLogger(ctx)
+	user, err := a.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := a.AddressRepo.GetByUserID(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	insurance, err := a.InsuranceRepo.GetByUserID(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	appointments, err := a.AppointmentRepo.GetByPatientID(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	var sessions []*model.RefreshToken
+	session, err := a.TokenRepo.GetByUserID(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if session != nil {
+		sessions = append(sessions, session)
+	}
+
+	log.Infof("user %s exported their data", id)
+	a.audit.Log(ctx, model.AuditRecordAccessed, id, map[string]string{
+		"purpose": "export",
+	})
+	return &model.UserDataExport{
+		ExportedAt:   time.Now(),
+		User:         user,
+		Addresses:    addresses,
+		Insurance:    insurance,
+		Appointments: appointments,
+		Sessions:     sessions,
+	}, nil
+}
+
+// RequestDeletion schedules the anonymization of the account and revokes its
+// sessions, it returns the date after which the data will be erased.
+func (a *AccountService) RequestDeletion(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.RequestDeletion")
+	defer span.End()
+	user, err := a.getUser(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = user.RequestDeletion()
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = a.UserRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := a.UserRepo.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+		return a.TokenRepo.DeleteByUserID(ctx, user.ID)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	logger.GetContextLogger(ctx).Infof("user %s requested the deletion of their account", id)
+	a.audit.Log(ctx, model.AuditDeletionRequest, id, nil)
+	return a.ScheduledDeletion(user), nil
+}
+
+func (a *AccountService) CancelDeletion(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "AccountService.CancelDeletion")
+	defer span.End()
+	user, err := a.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = user.CancelDeletion()
+	if err != nil {
+		return err
+	}
+	err = a.UserRepo.Update(ctx, user)
+	if err != nil {
+		return err
+	}
+	logger.GetContextLogger(ctx).Infof("user %s cancelled the deletion of their account", id)
+	a.audit.Log(ctx, model.AuditDeletionCancel, id, nil)
+	return nil
+}
+
+func (a *AccountService) ScheduledDeletion(user *model.User) time.Time {
+	return user.DeletionRequestedAt.Time.Add(a.gracePeriod)
+}
+
+// AnonymizeExpiredAccounts erases the personal data of every account whose
+// deletion grace period is over. Appointments and insurance are kept because
+// we are required to retain clinical and billing records.
+func (a *AccountService) AnonymizeExpiredAccounts(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.AnonymizeExpiredAccounts")
+	defer span.End()
+	log := logger.GetContextLogger(ctx)
+	users, err := a.UserRepo.GetPendingDeletion(ctx, time.Now().Add(-a.gracePeriod))
+	if err != nil {
+		return 0, err
+	}
+
+	anonymized := 0
+	for _, user := range users {
+		err = a.anonymize(ctx, user)
+		if err != nil {
+			log.Errorf("error anonymizing user %s: %s", user.ID, err)
+			continue
+		}
+		anonymized++
+	}
+	return anonymized, nil
+}
+
+func (a *AccountService) anonymize(ctx context.Context, user *model.User) error {
+	user.Anonymize()
+	err := a.UserRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := a.UserRepo.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+		err = a.AddressRepo.DeleteByUserID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		err = a.PasswordRepo.DeleteByUserID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		err = a.TokenRepo.DeleteByUserID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		a.audit.LogAs(ctx, model.AuditUserAnonymized, uuid.Nil, user.ID, nil)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.GetContextLogger(ctx).Infof("user %s anonymized", user.ID)
+	return nil
+}
+
+// MarkDeceased records the date of death of a patient, cancels their future
+// appointments and revokes their sessions. It returns the number of cancelled
+// appointments, each one is published to the webhooks.
+func (a *AccountService) MarkDeceased(ctx context.Context, actorID, patientID uuid.UUID, date time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.MarkDeceased")
+	defer span.End()
+	log := logger.GetContextLogger(ctx)
+	user, err := a.getUser(ctx, patientID)
+	if err != nil {
+		return 0, err
+	}
+	if user.Rol != model.PatientRole {
+		return 0, ErrNotAPatient
+	}
+	err = user.MarkDeceased(date)
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	err = a.UserRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := a.UserRepo.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+		appointments, err := a.AppointmentRepo.CancelFutureByPatientID(ctx, user.ID, model.CancellationPatientDeceased)
+		if err != nil {
+			return err
+		}
+		for _, appointment := range appointments {
+			err = a.webhooks.Publish(ctx, model.WebhookAppointmentCancelled, model.AppointmentCancelledData{
+				ID:          appointment.ID,
+				PatientID:   appointment.PatientID,
+				DoctorID:    appointment.DoctorID,
+				Date:        appointment.Date,
+				CancelledAt: appointment.CancelledAt.Time,
+				Reason:      appointment.CancellationReason.String,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cancelled = len(appointments)
+		err = a.TokenRepo.DeleteByUserID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		a.audit.LogAs(ctx, model.AuditPatientDeceased, actorID, user.ID, map[string]string{
+			"deceased_at":            date.Format(time.DateOnly),
+			"cancelled_appointments": strconv.Itoa(cancelled),
+		})
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	log.Infof("user %s marked as deceased by %s, %d appointments cancelled", user.ID, actorID, cancelled)
+	return cancelled, nil
+}
+
+// RunAnonymizer checks for expired deletion requests every interval until the
+// context is cancelled.
+func (a *AccountService) RunAnonymizer(ctx context.Context, interval time.Duration) {
+	log := logger.GetContextLogger(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := a.AnonymizeExpiredAccounts(ctx)
+		if err != nil {
+			log.Error(err)
+		} else if n > 0 {
+			log.Infof("%d accounts anonymized", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+	"github.com/oaxacos/vitacare/internal/domain/service/audit"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/tracing"
+)
+
+const (
+	// RotationGracePeriod is how long the previous key keeps working after a
+	// rotation, so the clients can deploy the new one.
+	RotationGracePeriod = 24 * time.Hour
+	// lastUsedInterval throttles the writes of the last use, a busy key would
+	// update its row on every request otherwise.
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrServiceAccountNotFound = apperror.NotFound("service_account_not_found", "service account not found")
+	ErrServiceAccountExists   = apperror.Conflict("service_account_exists", "service account %s already exists")
+	ErrAPIKeyNotFound         = apperror.NotFound("api_key_not_found", "api key not found")
+	ErrInvalidAPIKey          = apperror.Unauthorized("invalid_api_key", "invalid api key")
+)
+
+type APIKeyService struct {
+	AccountRepo repository.ServiceAccountRepository
+	KeyRepo     repository.APIKeyRepository
+	audit       audit.AuditLogger
+	now         func() time.Time
+}
+
+func NewAPIKeyService(accountRepo repository.ServiceAccountRepository, keyRepo repository.APIKeyRepository, auditLogger audit.AuditLogger) *APIKeyService {
+	return &APIKeyService{
+		AccountRepo: accountRepo,
+		KeyRepo:     keyRepo,
+		audit:       auditLogger,
+		now:         time.Now,
+	}
+}
+
+// KeyOptions are the settings of a new api key.
+type KeyOptions struct {
+	Name   string
+	Scopes []model.Scope
+	// RateLimit in requests per minute, 0 uses the limit of the routes
+	RateLimit int
+	ExpiresAt time.Time
+}
+
+func (s *APIKeyService) CreateServiceAccount(ctx context.Context, createdBy uuid.UUID, name, description string) (*model.ServiceAccount, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateServiceAccount")
+	defer span.End()
+	account := model.NewServiceAccount(name, description, createdBy)
+	if err := s.AccountRepo.Save(ctx, account); err != nil {
+		// the name is unique, two requests can't both create it
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrServiceAccountExists.WithArgs(name)
+		}
+		logger.GetContextLogger(ctx).Error(err)
+		return nil, err
+	}
+	s.audit.Log(ctx, model.AuditServiceAccountCreated, account.ID, map[string]string{
+		"name": name,
+	})
+	return account, nil
+}
+
+func (s *APIKeyService) ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.ListServiceAccounts")
+	defer span.End()
+	return s.AccountRepo.List(ctx)
+}
+
+func (s *APIKeyService) getServiceAccount(ctx context.Context, id uuid.UUID) (*model.ServiceAccount, error) {
+	account, err := s.AccountRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+// getKey returns the key of the service account, the keys of other accounts
+// are not found.
+func (s *APIKeyService) getKey(ctx context.Context, serviceAccountID, keyID uuid.UUID) (*model.APIKey, error) {
+	key, err := s.KeyRepo.GetByID(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	if key.ServiceAccountID != serviceAccountID {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// CreateKey returns the new key and its value, the value is only known now.
+func (s *APIKeyService) CreateKey(ctx context.Context, serviceAccountID uuid.UUID, opts KeyOptions) (*model.APIKey, string, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateKey")
+	defer span.End()
+	if _, err := s.getServiceAccount(ctx, serviceAccountID); err != nil {
+		return nil, "", err
+	}
+	key, value, err := s.newKey(serviceAccountID, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.KeyRepo.Save(ctx, key); err != nil {
+		logger.GetContextLogger(ctx).Error(err)
+		return nil, "", err
+	}
+	s.audit.Log(ctx, model.AuditAPIKeyCreated, serviceAccountID, map[string]string{
+		"api_key_id": key.ID.String(),
+		"prefix":     key.Prefix,
+	})
+	return key, value, nil
+}
+
+func (s *APIKeyService) ListKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.ListKeys")
+	defer span.End()
+	if _, err := s.getServiceAccount(ctx, serviceAccountID); err != nil {
+		return nil, err
+	}
+	return s.KeyRepo.GetByServiceAccountID(ctx, serviceAccountID)
+}
+
+// RotateKey replaces the key by a new one with the same settings, the old
+// key expires after RotationGracePeriod.
+func (s *APIKeyService) RotateKey(ctx context.Context, serviceAccountID, keyID uuid.UUID) (*model.APIKey, string, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RotateKey")
+	defer span.End()
+	var (
+		rotated *model.APIKey
+		value   string
+	)
+	err := s.KeyRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		old, err := s.getKey(ctx, serviceAccountID, keyID)
+		if err != nil {
+			return err
+		}
+		if old.RevokedAt.Valid {
+			return model.ErrAPIKeyRevoked
+		}
+		opts := KeyOptions{
+			Name:      old.Name,
+			Scopes:    old.Scopes,
+			RateLimit: old.RateLimit,
+		}
+		if old.ExpiresAt.Valid {
+			opts.ExpiresAt = old.ExpiresAt.Time
+		}
+		rotated, value, err = s.newKey(serviceAccountID, opts)
+		if err != nil {
+			return err
+		}
+		if err := s.KeyRepo.Save(ctx, rotated); err != nil {
+			return err
+		}
+		old.ExpireAt(s.now().Add(RotationGracePeriod))
+		return s.KeyRepo.Update(ctx, old)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	s.audit.Log(ctx, model.AuditAPIKeyRotated, serviceAccountID, map[string]string{
+		"api_key_id":     keyID.String(),
+		"new_api_key_id": rotated.ID.String(),
+	})
+	return rotated, value, nil
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, serviceAccountID, keyID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeKey")
+	defer span.End()
+	err := s.KeyRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		key, err := s.getKey(ctx, serviceAccountID, keyID)
+		if err != nil {
+			return err
+		}
+		if err := key.Revoke(s.now()); err != nil {
+			return err
+		}
+		return s.KeyRepo.Update(ctx, key)
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.Log(ctx, model.AuditAPIKeyRevoked, serviceAccountID, map[string]string{
+		"api_key_id": keyID.String(),
+	})
+	return nil
+}
+
+// Authenticate returns the active key of value. The reason of a failure is
+// only logged, the client always gets ErrInvalidAPIKey.
+func (s *APIKeyService) Authenticate(ctx context.Context, value string) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+	log := logger.GetContextLogger(ctx)
+	prefix, secret, ok := model.ParseAPIKey(value)
+	if !ok {
+		log.Debugf("malformed api key")
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.KeyRepo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Debugf("api key %s not found", prefix)
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	now := s.now()
+	if !key.Verify(secret) || !key.IsActive(now) {
+		log.Infof("api key %s is invalid, revoked or expired", prefix)
+		return nil, ErrInvalidAPIKey
+	}
+
+	if !key.LastUsedAt.Valid || now.Sub(key.LastUsedAt.Time) >= lastUsedInterval {
+		if err := s.KeyRepo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			log.Errorf("error updating the last use of api key %s: %s", prefix, err)
+		}
+		key.LastUsedAt = sql.NullTime{Time: now, Valid: true}
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) newKey(serviceAccountID uuid.UUID, opts KeyOptions) (*model.APIKey, string, error) {
+	prefix, secret, err := generateKeyParts()
+	if err != nil {
+		return nil, "", err
+	}
+	key, value, err := model.NewAPIKey(serviceAccountID, opts.Name, prefix, secret, opts.Scopes, opts.RateLimit)
+	if err != nil {
+		return nil, "", err
+	}
+	if !opts.ExpiresAt.IsZero() {
+		key.ExpireAt(opts.ExpiresAt)
+	}
+	return key, value, nil
+}
+
+// generateKeyParts returns a short prefix to find the key and a 256 bits
+// secret.
+func generateKeyParts() (string, string, error) {
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(prefix), base64.RawURLEncoding.EncodeToString(secret), nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/application/dto"
 	"github.com/oaxacos/vitacare/internal/domain/model"
@@ -12,15 +14,28 @@ type TokenService interface {
 	GenerateToken(ctx context.Context, user *model.User) (string, string, error)
 	ValidateRefreshToken(ctx context.Context, refreshToken string) (*model.RefreshToken, error)
 	VerifyAccessToken(ctx context.Context, token string) (*token.AccessTokenClaims, error)
-	DeleteRefreshTokenByUser(userID uuid.UUID) error
-	DeleteRefreshToken(token string) error
+	DeleteRefreshTokenByUser(ctx context.Context, userID uuid.UUID) error
+	DeleteRefreshToken(ctx context.Context, token string) error
+	PurgeExpiredTokens(ctx context.Context) (int, error)
 }
 
 type UserService interface {
 	CreateUser(ctx context.Context, user dto.UserDto) (*model.User, error)
+	CreateUserWithRole(ctx context.Context, user dto.UserDto, role model.UserRole) (*model.User, error)
 	ExistUser(ctx context.Context, email string) error
 	LoginUser(ctx context.Context, data dto.UserLoginDto) (*model.User, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	ResetPassword(ctx context.Context, id uuid.UUID, plainText string) error
 	UpdateUserRole(ctx context.Context, id uuid.UUID, role string) error
 	UpdateUserInfo(ctx context.Context, id uuid.UUID, data dto.UpdateUserDto) error
+	ListActivePatients(ctx context.Context, limit, offset int) ([]*model.User, int, error)
+}
+
+type AccountService interface {
+	ExportUserData(ctx context.Context, id uuid.UUID) (*model.UserDataExport, error)
+	RequestDeletion(ctx context.Context, id uuid.UUID) (time.Time, error)
+	CancelDeletion(ctx context.Context, id uuid.UUID) error
+	AnonymizeExpiredAccounts(ctx context.Context) (int, error)
+	MarkDeceased(ctx context.Context, actorID, patientID uuid.UUID, date time.Time) (int, error)
 }
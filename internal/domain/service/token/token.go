@@ -5,7 +5,10 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"errors"
+	"github.com/oaxacos/vitacare/pkg/apperror"
 	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/metrics"
+	"github.com/oaxacos/vitacare/pkg/tracing"
 	"github.com/oaxacos/vitacare/pkg/utils"
 	"math/rand"
 	"time"
@@ -25,7 +28,7 @@ type AccessTokenClaims struct {
 }
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
+	ErrInvalidToken = apperror.Unauthorized("invalid_token", "invalid token")
 )
 
 type TokenSvc struct {
@@ -47,6 +50,8 @@ func NewTokenService(conf *config.Config, repo repository.RefreshTokenRepository
 }
 
 func (t *TokenSvc) GenerateToken(ctx context.Context, user *model.User) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "TokenSvc.GenerateToken")
+	defer span.End()
 	accessToken, err := t.GenerateAccessToken(ctx, user)
 	if err != nil {
 		return "", "", err
@@ -59,7 +64,14 @@ func (t *TokenSvc) GenerateToken(ctx context.Context, user *model.User) (string,
 }
 
 func (t *TokenSvc) GenerateAccessToken(ctx context.Context, user *model.User) (string, error) {
-	return utils.GenerateAccessToken(user, t.accessExpirationTime, t.accessTokenKey)
+	ctx, span := tracing.Start(ctx, "TokenSvc.GenerateAccessToken")
+	defer span.End()
+	token, err := utils.GenerateAccessToken(user, t.accessExpirationTime, t.accessTokenKey)
+	if err != nil {
+		return "", err
+	}
+	metrics.IncTokenIssued(metrics.AccessToken)
+	return token, nil
 }
 
 func (t *TokenSvc) generateRefreshToken(ctx context.Context, user *model.User) (string, error) {
@@ -91,11 +103,23 @@ func (t *TokenSvc) generateRefreshToken(ctx context.Context, user *model.User) (
 		logs.Error(err)
 		return "", err
 	}
+	metrics.IncTokenIssued(metrics.RefreshToken)
 	return tokenString, nil
 }
 
 func (t *TokenSvc) VerifyAccessToken(ctx context.Context, token string) (*AccessTokenClaims, error) {
-	return t.VerifyAccessToken(ctx, token)
+	_, span := tracing.Start(ctx, "TokenSvc.VerifyAccessToken")
+	defer span.End()
+	claims, err := utils.VerifyAccessToken(token, t.accessTokenKey)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &AccessTokenClaims{
+		UserID:           claims.UserID,
+		Email:            claims.Email,
+		Rol:              claims.Rol,
+		RegisteredClaims: claims.RegisteredClaims,
+	}, nil
 }
 
 func (t *TokenSvc) isExpired(token *model.RefreshToken) bool {
@@ -111,6 +135,8 @@ func (t *TokenSvc) generateRandomToken() (string, error) {
 }
 
 func (t *TokenSvc) ValidateRefreshToken(ctx context.Context, refreshToken string) (*model.RefreshToken, error) {
+	ctx, span := tracing.Start(ctx, "TokenSvc.ValidateRefreshToken")
+	defer span.End()
 	log := logger.GetContextLogger(ctx)
 	beforeToken, err := t.repo.GetByToken(ctx, refreshToken)
 	if err != nil {
@@ -133,19 +159,23 @@ func (t *TokenSvc) ValidateRefreshToken(ctx context.Context, refreshToken string
 	return beforeToken, nil
 }
 
-func (t *TokenSvc) DeleteRefreshTokenByUser(userID uuid.UUID) error {
-	token, err := t.repo.GetByUserID(context.Background(), userID)
+func (t *TokenSvc) DeleteRefreshTokenByUser(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TokenSvc.DeleteRefreshTokenByUser")
+	defer span.End()
+	token, err := t.repo.GetByUserID(ctx, userID)
 	if err != nil {
 		return err
 	}
 	if token == nil {
 		return nil
 	}
-	return t.repo.Delete(context.Background(), token.ID)
+	return t.repo.Delete(ctx, token.ID)
 }
 
-func (t *TokenSvc) DeleteRefreshToken(token string) error {
-	err := t.repo.DeleteByToken(context.Background(), token)
+func (t *TokenSvc) DeleteRefreshToken(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "TokenSvc.DeleteRefreshToken")
+	defer span.End()
+	err := t.repo.DeleteByToken(ctx, token)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil
@@ -153,3 +183,10 @@ func (t *TokenSvc) DeleteRefreshToken(token string) error {
 	}
 	return nil
 }
+
+// PurgeExpiredTokens deletes the refresh tokens that can not be used anymore.
+func (t *TokenSvc) PurgeExpiredTokens(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "TokenSvc.PurgeExpiredTokens")
+	defer span.End()
+	return t.repo.DeleteExpired(ctx, time.Now())
+}
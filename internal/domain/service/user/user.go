@@ -2,61 +2,111 @@ package user
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/application/dto"
 	"github.com/oaxacos/vitacare/internal/domain/model"
 	"github.com/oaxacos/vitacare/internal/domain/repository"
+	"github.com/oaxacos/vitacare/internal/domain/service/audit"
+	"github.com/oaxacos/vitacare/internal/domain/service/webhook"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/i18n"
 	"github.com/oaxacos/vitacare/pkg/logger"
-	"github.com/uptrace/bun"
+	"github.com/oaxacos/vitacare/pkg/metrics"
+	"github.com/oaxacos/vitacare/pkg/tracing"
+	"github.com/oaxacos/vitacare/pkg/validator"
+	"strings"
+	"time"
 )
 
 var (
-	ErrUserAlreadyExist = errors.New("user already exist")
-	ErrNoUserWithEmail  = errors.New("invalid credentials")
-	ErrNoUserWithID     = errors.New("user not found")
+	ErrUserAlreadyExist = apperror.Conflict("user_already_exists", "user already exist")
+	ErrNoUserWithEmail  = apperror.Unauthorized("invalid_credentials", "invalid credentials")
+	ErrNoUserWithID     = apperror.NotFound("user_not_found", "user not found")
+	ErrEmailNotFound    = apperror.NotFound("user_email_not_found", "no user with that email")
+	ErrNoDataToUpdate   = apperror.Validation("no_data_to_update", "no data to update")
 )
 
 type UserService struct {
 	UserRepo     repository.UserRepository
 	PasswordRepo repository.PasswordRepository
+	audit        audit.AuditLogger
+	webhooks     webhook.Publisher
 }
 
-func NewUserService(userRepo repository.UserRepository, passwordRepo repository.PasswordRepository) *UserService {
+func NewUserService(userRepo repository.UserRepository, passwordRepo repository.PasswordRepository, auditLogger audit.AuditLogger, webhooks webhook.Publisher) *UserService {
 	return &UserService{
 		UserRepo:     userRepo,
 		PasswordRepo: passwordRepo,
+		audit:        auditLogger,
+		webhooks:     webhooks,
 	}
 }
 
 func (u *UserService) CreateUser(ctx context.Context, user dto.UserDto) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+	if user.Language == "" {
+		user.Language = i18n.FromContext(ctx)
+	}
+	// the password is hashed by the model
+	start := time.Now()
 	newUser := model.NewPatientUser(user)
+	metrics.ObserveBcrypt(metrics.BcryptHash, start)
+	return u.createUser(ctx, newUser)
+}
+
+// CreateUserWithRole creates a user with a role other than patient, it is
+// used by the cli to bootstrap the first admin.
+func (u *UserService) CreateUserWithRole(ctx context.Context, user dto.UserDto, role model.UserRole) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUserWithRole")
+	defer span.End()
+	start := time.Now()
+	newUser := model.NewUser(user, role)
+	metrics.ObserveBcrypt(metrics.BcryptHash, start)
+	return u.createUser(ctx, newUser)
+}
+
+func (u *UserService) createUser(ctx context.Context, newUser *model.User) (*model.User, error) {
 	log := logger.GetContextLogger(ctx)
 	//save user
-	err := u.UserRepo.WithTransaction(ctx, func(tx *bun.Tx) error {
+	err := u.UserRepo.WithinTransaction(ctx, func(ctx context.Context) error {
 		// save user
-		err := u.UserRepo.Save(ctx, tx, newUser)
+		err := u.UserRepo.Save(ctx, newUser)
 		if err != nil {
 			log.Error(err)
 			return err
 		}
 		// save user password
-		err = u.PasswordRepo.Save(ctx, tx, newUser.Password)
+		err = u.PasswordRepo.Save(ctx, newUser.Password)
 		if err != nil {
 			log.Error(err)
 			return err
 		}
-		return nil
+		if newUser.Rol != model.PatientRole {
+			return nil
+		}
+		return u.webhooks.Publish(ctx, model.WebhookPatientRegistered, model.PatientRegisteredData{
+			ID:        newUser.ID,
+			CreatedAt: newUser.CreatedAt,
+		})
 	})
 	if err != nil {
 		log.Error(err)
 		return nil, err
 	}
+	u.audit.Log(ctx, model.AuditUserCreated, newUser.ID, map[string]string{
+		"role": string(newUser.Rol),
+	})
 	return newUser, nil
 }
 
 func (u *UserService) ExistUser(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ExistUser")
+	defer span.End()
 	_, err := u.UserRepo.GetByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -68,21 +118,61 @@ func (u *UserService) ExistUser(ctx context.Context, email string) error {
 }
 
 func (u *UserService) LoginUser(ctx context.Context, data dto.UserLoginDto) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.LoginUser")
+	defer span.End()
+	user, err := u.loginUser(ctx, data)
+	if err != nil {
+		metrics.IncLogin(metrics.LoginFailure)
+		return nil, err
+	}
+	metrics.IncLogin(metrics.LoginSuccess)
+	return user, nil
+}
+
+func (u *UserService) loginUser(ctx context.Context, data dto.UserLoginDto) (*model.User, error) {
 	user, err := u.UserRepo.GetByEmail(ctx, data.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			u.audit.LogAs(ctx, model.AuditLoginFailed, uuid.Nil, uuid.Nil, map[string]string{
+				"email_hash": emailHash(data.Email),
+				"reason":     "unknown email",
+			})
 			return nil, ErrNoUserWithEmail
 		}
 		return nil, err
 	}
+	// the compare time includes the read of the hash, a lookup by key
+	start := time.Now()
 	err = u.PasswordRepo.VerifyPasswordText(ctx, user.ID, data.Password)
+	metrics.ObserveBcrypt(metrics.BcryptCompare, start)
 	if err != nil {
+		if errors.Is(err, model.ErrorPasswordIncorrect) {
+			u.audit.LogAs(ctx, model.AuditLoginFailed, uuid.Nil, user.ID, map[string]string{
+				"reason": "invalid password",
+			})
+		}
 		return nil, err
 	}
+	if !user.CanAuthenticate() {
+		u.audit.LogAs(ctx, model.AuditLoginFailed, uuid.Nil, user.ID, map[string]string{
+			"reason": "inactive account",
+		})
+		return nil, model.ErrUserInactive
+	}
+	u.audit.LogAs(ctx, model.AuditLogin, user.ID, user.ID, nil)
 	return user, nil
 }
 
+// emailHash lets the audit trail group the failed logins of an unknown email
+// without storing the email, only the first bytes of its sha256 are kept.
+func emailHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:8])
+}
+
 func (u *UserService) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByID")
+	defer span.End()
 	user, err := u.UserRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -93,7 +183,65 @@ func (u *UserService) GetByID(ctx context.Context, id uuid.UUID) (*model.User, e
 	return user, nil
 }
 
+// EnsureCanAuthenticate returns model.ErrUserInactive when the user can't use
+// the access tokens issued before, like a deceased or deleted user.
+func (u *UserService) EnsureCanAuthenticate(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "UserService.EnsureCanAuthenticate")
+	defer span.End()
+	user, err := u.UserRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ErrUserInactive
+		}
+		return err
+	}
+	if !user.CanAuthenticate() {
+		return model.ErrUserInactive
+	}
+	return nil
+}
+
+func (u *UserService) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByEmail")
+	defer span.End()
+	user, err := u.UserRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEmailNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetPassword replaces the password of the user, the sessions are not
+// revoked here so the caller decides.
+func (u *UserService) ResetPassword(ctx context.Context, id uuid.UUID, plainText string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	password := model.NewPassword(id, plainText)
+	start := time.Now()
+	if err := password.SetHash(); err != nil {
+		return err
+	}
+	metrics.ObserveBcrypt(metrics.BcryptHash, start)
+	err := u.UserRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := u.PasswordRepo.DeleteByUserID(ctx, id); err != nil {
+			return err
+		}
+		return u.PasswordRepo.Save(ctx, password)
+	})
+	if err != nil {
+		return err
+	}
+	u.audit.Log(ctx, model.AuditPasswordReset, id, nil)
+	return nil
+}
+
 func (u *UserService) UpdateUserRole(ctx context.Context, id uuid.UUID, role string) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUserRole")
+	defer span.End()
 
 	user, err := u.UserRepo.GetByID(ctx, id)
 	if err != nil {
@@ -102,21 +250,37 @@ func (u *UserService) UpdateUserRole(ctx context.Context, id uuid.UUID, role str
 		}
 		return err
 	}
+	previousRole := user.Rol
 	err = user.UpdateRole(role)
 	if err != nil {
 		return err
 	}
-	logger.GetContextLogger(ctx).Infof("user %s updated to role %s", user.Email, user.Rol)
-	err = u.UserRepo.Update(user)
+	logger.GetContextLogger(ctx).Infof("user %s updated to role %s", user.ID, user.Rol)
+	err = u.UserRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := u.UserRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return u.webhooks.Publish(ctx, model.WebhookUserRoleChanged, model.RoleChangedData{
+			ID:   user.ID,
+			From: previousRole,
+			To:   user.Rol,
+		})
+	})
 	if err != nil {
 		return err
 	}
+	u.audit.Log(ctx, model.AuditRoleChanged, user.ID, map[string]string{
+		"from": string(previousRole),
+		"to":   string(user.Rol),
+	})
 	return nil
 }
 
 func (u *UserService) UpdateUserInfo(ctx context.Context, id uuid.UUID, data dto.UpdateUserDto) error {
-	if data.FirstName == "" && data.LastName == "" && data.Dni == "" && data.Phone == "" && data.BirthDate == "" {
-		return errors.New("no data to update")
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUserInfo")
+	defer span.End()
+	if data.FirstName == "" && data.LastName == "" && data.Dni == "" && data.Phone == "" && data.BirthDate == "" && data.Language == "" {
+		return ErrNoDataToUpdate
 	}
 	logger.GetContextLogger(ctx).Infof("updating user %s", id)
 
@@ -128,19 +292,41 @@ func (u *UserService) UpdateUserInfo(ctx context.Context, id uuid.UUID, data dto
 		return err
 	}
 
+	var fields []string
 	if data.FirstName != "" {
 		user.FirstName = data.FirstName
+		fields = append(fields, "first_name")
 	}
 	if data.LastName != "" {
 		user.LastName = data.LastName
+		fields = append(fields, "last_name")
 	}
 	if data.Dni != "" {
-		user.DNI = data.Dni
+		user.DNI = strings.ToUpper(strings.TrimSpace(data.Dni))
+		fields = append(fields, "dni")
 	}
 	if data.Phone != "" {
-		user.Phone = data.Phone
+		user.Phone = validator.NormalizePhone(data.Phone)
+		fields = append(fields, "phone")
+	}
+	if data.Language != "" {
+		user.Language = data.Language
+		fields = append(fields, "language")
 	}
 	//TODO: find a way to handle birthdate, can be iso or yy-mm-dd, or dd-mm-yy
 
-	return u.UserRepo.Update(user)
+	err = u.UserRepo.Update(ctx, user)
+	if err != nil {
+		return err
+	}
+	u.audit.Log(ctx, model.AuditProfileUpdated, user.ID, map[string]string{
+		"fields": strings.Join(fields, ","),
+	})
+	return nil
+}
+
+func (u *UserService) ListActivePatients(ctx context.Context, limit, offset int) ([]*model.User, int, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListActivePatients")
+	defer span.End()
+	return u.UserRepo.ListActivePatients(ctx, limit, offset)
 }
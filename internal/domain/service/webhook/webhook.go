@@ -0,0 +1,381 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/repository"
+	"github.com/oaxacos/vitacare/internal/domain/service/audit"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/metrics"
+	"github.com/oaxacos/vitacare/pkg/tracing"
+)
+
+const (
+	secretPrefix = "whsec_"
+	// maxErrorBody is the part of the response body kept in the delivery log
+	maxErrorBody = 512
+)
+
+var (
+	ErrSubscriptionNotFound = apperror.NotFound("webhook_not_found", "webhook subscription not found")
+	ErrDeliveryNotFound     = apperror.NotFound("webhook_delivery_not_found", "webhook delivery not found")
+	ErrInvalidURL           = apperror.Validation("invalid_webhook_url", "the webhook url must be an absolute %s url")
+	ErrURLNotPublic         = apperror.Validation("webhook_url_not_public", "the webhook url must point to a public address")
+
+	errAddressNotPublic = errors.New("the address is not public")
+)
+
+// Publisher sends the domain events to the subscribed systems. The services
+// call it in the transaction of the change, so a delivery is queued only
+// when the change is saved and the change fails when it can't be queued.
+type Publisher interface {
+	Publish(ctx context.Context, event model.WebhookEvent, data any) error
+}
+
+// NopPublisher drops the events, it is used when the webhooks are disabled.
+type NopPublisher struct{}
+
+func (NopPublisher) Publish(ctx context.Context, event model.WebhookEvent, data any) error {
+	return nil
+}
+
+type WebhookService struct {
+	SubscriptionRepo repository.WebhookSubscriptionRepository
+	DeliveryRepo     repository.WebhookDeliveryRepository
+	audit            audit.AuditLogger
+	client           *http.Client
+	policy           model.WebhookRetryPolicy
+	allowHTTP        bool
+	allowPrivate     bool
+	batchSize        int
+	timeout          time.Duration
+	now              func() time.Time
+}
+
+func NewWebhookService(conf *config.Config, subscriptions repository.WebhookSubscriptionRepository, deliveries repository.WebhookDeliveryRepository, auditLogger audit.AuditLogger) *WebhookService {
+	timeout := time.Duration(conf.Webhooks.Timeout) * time.Second
+	return &WebhookService{
+		SubscriptionRepo: subscriptions,
+		DeliveryRepo:     deliveries,
+		audit:            auditLogger,
+		client:           newClient(conf.Webhooks.AllowPrivateNetworks),
+		policy: model.WebhookRetryPolicy{
+			MaxAttempts: conf.Webhooks.MaxAttempts,
+			Base:        time.Duration(conf.Webhooks.BackoffBase) * time.Second,
+			Max:         time.Duration(conf.Webhooks.BackoffMax) * time.Second,
+		},
+		allowHTTP:    conf.Webhooks.AllowHTTP,
+		allowPrivate: conf.Webhooks.AllowPrivateNetworks,
+		batchSize:    conf.Webhooks.BatchSize,
+		timeout:      timeout,
+		now:          time.Now,
+	}
+}
+
+// CreateSubscription returns the subscription with its secret, the receiver
+// needs it to check the signature of the deliveries.
+func (w *WebhookService) CreateSubscription(ctx context.Context, createdBy uuid.UUID, rawURL, description string, events []model.WebhookEvent) (*model.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateSubscription")
+	defer span.End()
+	if err := w.validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := model.NewWebhookSubscription(rawURL, description, secret, events, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.SubscriptionRepo.Save(ctx, subscription); err != nil {
+		logger.GetContextLogger(ctx).Error(err)
+		return nil, err
+	}
+	w.audit.Log(ctx, model.AuditWebhookCreated, subscription.ID, map[string]string{
+		"url": rawURL,
+	})
+	return subscription, nil
+}
+
+func (w *WebhookService) ListSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListSubscriptions")
+	defer span.End()
+	return w.SubscriptionRepo.List(ctx)
+}
+
+// DeleteSubscription stops the deliveries of the subscription and drops its
+// log.
+func (w *WebhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteSubscription")
+	defer span.End()
+	subscription, err := w.getSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := w.SubscriptionRepo.Delete(ctx, id); err != nil {
+		logger.GetContextLogger(ctx).Error(err)
+		return err
+	}
+	w.audit.Log(ctx, model.AuditWebhookDeleted, id, map[string]string{
+		"url": subscription.URL,
+	})
+	return nil
+}
+
+func (w *WebhookService) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, int, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+	return w.DeliveryRepo.List(ctx, filter)
+}
+
+// RetryDelivery sends a dead delivery again on the next poll.
+func (w *WebhookService) RetryDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.RetryDelivery")
+	defer span.End()
+	delivery, err := w.DeliveryRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	if err := delivery.Retry(w.now()); err != nil {
+		return nil, err
+	}
+	if err := w.DeliveryRepo.Update(ctx, delivery); err != nil {
+		logger.GetContextLogger(ctx).Error(err)
+		return nil, err
+	}
+	w.audit.Log(ctx, model.AuditWebhookRetried, delivery.SubscriptionID, map[string]string{
+		"delivery_id": delivery.ID.String(),
+	})
+	return delivery, nil
+}
+
+// Publish queues a delivery of event for every subscription, they are sent
+// by the dispatcher. Call it within the transaction of the change so the
+// deliveries are saved with it.
+func (w *WebhookService) Publish(ctx context.Context, event model.WebhookEvent, data any) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.Publish")
+	defer span.End()
+	subscriptions, err := w.SubscriptionRepo.GetByEvent(ctx, event)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload := model.WebhookPayload{
+		ID:        uuid.New(),
+		Event:     event,
+		CreatedAt: w.now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	deliveries := make([]*model.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, model.NewWebhookDelivery(subscription.ID, payload, body))
+	}
+	if err := w.DeliveryRepo.Save(ctx, deliveries...); err != nil {
+		return err
+	}
+	logger.GetContextLogger(ctx).Debugf("webhook event %s queued for %d subscriptions", event, len(deliveries))
+	return nil
+}
+
+// DeliverDue sends the deliveries that are due and returns how many were
+// attempted.
+func (w *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeliverDue")
+	defer span.End()
+	// the lease covers the attempts of the whole batch, a crashed instance
+	// leaves its deliveries to the others once it expires
+	lease := w.timeout*time.Duration(w.batchSize) + time.Minute
+	deliveries, err := w.DeliveryRepo.ClaimDue(ctx, w.now(), lease, w.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range deliveries {
+		// the delivery is claimed again once the lease expires
+		if err := w.deliver(ctx, delivery); err != nil {
+			logger.GetContextLogger(ctx).Errorf("error delivering webhook %s: %s", delivery.ID, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// RunDispatcher sends the due deliveries every interval until the context is
+// cancelled.
+func (w *WebhookService) RunDispatcher(ctx context.Context, interval time.Duration) {
+	log := logger.GetContextLogger(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := w.DeliverDue(ctx)
+		if err != nil {
+			log.Error(err)
+		} else if n > 0 {
+			log.Debugf("%d webhook deliveries attempted", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *WebhookService) deliver(ctx context.Context, delivery *model.WebhookDelivery) error {
+	log := logger.GetContextLogger(ctx)
+	subscription, err := w.getSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	status, err := w.send(ctx, subscription, delivery)
+	now := w.now()
+	result := "delivered"
+	if err != nil {
+		delivery.Fail(now, status, err.Error(), w.policy)
+		result = "failed"
+		if delivery.Status == model.WebhookDead {
+			result = "dead"
+		}
+		log.Infof("webhook delivery %s to %s %s: %s", delivery.ID, subscription.URL, result, err)
+	} else {
+		delivery.Succeed(now, status)
+	}
+	metrics.IncWebhookDelivery(string(delivery.Event), result)
+	return w.DeliveryRepo.Update(ctx, delivery)
+}
+
+// send posts the payload and returns the status of the response, any status
+// other than 2xx is an error.
+func (w *WebhookService) send(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vitacare-webhooks")
+	req.Header.Set("X-Vitacare-Event", string(delivery.Event))
+	req.Header.Set("X-Vitacare-Delivery", delivery.ID.String())
+	req.Header.Set(model.WebhookSignatureHeader, subscription.Sign(w.now(), delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return resp.StatusCode, nil
+}
+
+func (w *WebhookService) getSubscription(ctx context.Context, id uuid.UUID) (*model.WebhookSubscription, error) {
+	subscription, err := w.SubscriptionRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (w *WebhookService) validateURL(rawURL string) error {
+	scheme := "https"
+	if w.allowHTTP {
+		scheme = "http(s)"
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && !(w.allowHTTP && u.Scheme == "http")) {
+		return ErrInvalidURL.WithArgs(scheme)
+	}
+	if w.allowPrivate {
+		return nil
+	}
+	// the names are resolved when the deliveries are sent, the dialer
+	// checks the address they resolve to
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return ErrURLNotPublic
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublic(ip) {
+		return ErrURLNotPublic
+	}
+	return nil
+}
+
+// newClient doesn't follow redirects and, unless allowPrivate, refuses to
+// connect to an address that is not public, a subscription could point the
+// deliveries to the internal services and read them in the delivery log.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return fmt.Errorf("%w: %s", errAddressNotPublic, host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the receiver
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		// a redirect could send the payload somewhere else
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
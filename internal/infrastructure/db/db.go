@@ -1,49 +1,157 @@
 package db
 
 import (
-	"context"
+	"crypto/x509"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/tracing"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
 )
 
+const (
+	initialRetryDelay = time.Second
+	maxRetryDelay     = 30 * time.Second
+)
+
+var ErrInvalidRootCert = errors.New("sslrootcert has no valid certificate")
+
 type DBRepository struct {
 	*bun.DB
+	// Replica serves the list and report queries, it is nil when there is no
+	// replica configured
+	Replica *bun.DB
 }
 
 func NewConnection(conf *config.Config) (*DBRepository, error) {
 	configDB := conf.Database
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", configDB.Username, configDB.Password, configDB.Host,
-		configDB.Port, configDB.DbName)
-	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
-	db := bun.NewDB(sqldb, pgdialect.New())
-
-	if err := db.Ping(); err != nil {
+	db, err := open(configDB, configDB.Host, configDB.Port, configDB.Username, configDB.Password)
+	if err != nil {
+		return nil, err
+	}
+	if err := ping(db, configDB.ConnectRetries); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	return &DBRepository{
+	repo := &DBRepository{
 		DB: db,
-	}, nil
+	}
+
+	replicaConf := configDB.Replica
+	if replicaConf.Host != "" {
+		username, password := replicaConf.Username, replicaConf.Password
+		if username == "" {
+			username, password = configDB.Username, configDB.Password
+		}
+		replica, err := open(configDB, replicaConf.Host, replicaConf.Port, username, password)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		if err := ping(replica, configDB.ConnectRetries); err != nil {
+			_ = db.Close()
+			_ = replica.Close()
+			return nil, fmt.Errorf("failed to connect to read replica: %w", err)
+		}
+		repo.Replica = replica
+	}
+
+	return repo, nil
 }
 
-func (db *DBRepository) Close() error {
-	return db.DB.Close()
+func open(configDB config.Database, host string, port int, username, password string) (*bun.DB, error) {
+	// pgdriver panics on an invalid certificate, check it first
+	if configDB.SSLRootCert != "" {
+		rawCA, err := os.ReadFile(configDB.SSLRootCert)
+		if err != nil {
+			return nil, err
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(rawCA) {
+			return nil, ErrInvalidRootCert
+		}
+	}
+
+	options := []pgdriver.Option{
+		pgdriver.WithDSN(dsn(configDB, host, port, username, password)),
+	}
+	if configDB.StatementTimeout > 0 {
+		options = append(options, pgdriver.WithConnParams(map[string]interface{}{
+			"statement_timeout": configDB.StatementTimeout * 1000,
+		}))
+	}
+	sqldb := sql.OpenDB(pgdriver.NewConnector(options...))
+	sqldb.SetMaxOpenConns(configDB.MaxOpenConns)
+	sqldb.SetMaxIdleConns(configDB.MaxIdleConns)
+	sqldb.SetConnMaxLifetime(time.Duration(configDB.ConnMaxLifetime) * time.Second)
+	sqldb.SetConnMaxIdleTime(time.Duration(configDB.ConnMaxIdleTime) * time.Second)
+
+	db := bun.NewDB(sqldb, pgdialect.New())
+	db.AddQueryHook(tracing.NewQueryHook(configDB.DbName))
+	return db, nil
 }
 
-func (db *DBRepository) WithTransaction(context context.Context, fn func(*bun.Tx) error) error {
-	tx, err := db.DB.BeginTx(context, nil)
-	if err != nil {
-		return err
+func dsn(configDB config.Database, host string, port int, username, password string) string {
+	query := url.Values{}
+	sslMode := configDB.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	query.Set("sslmode", sslMode)
+	if configDB.SSLRootCert != "" {
+		query.Set("sslrootcert", configDB.SSLRootCert)
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:     "/" + configDB.DbName,
+		RawQuery: query.Encode(),
 	}
+	return u.String()
+}
 
-	if err := fn(&tx); err != nil {
-		return tx.Rollback()
+// ping waits for the database with an exponential backoff, so the api can
+// start together with the database in docker compose or kubernetes.
+func ping(db *bun.DB, retries int) error {
+	delay := initialRetryDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = db.Ping(); err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return err
+		}
+		logger.GetGlobalLogger().Warnf("database not ready, retrying in %s: %s", delay, err)
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
 	}
+}
+
+func (db *DBRepository) Close() error {
+	var replicaErr error
+	if db.Replica != nil {
+		replicaErr = db.Replica.Close()
+	}
+	return errors.Join(db.DB.Close(), replicaErr)
+}
+
+// uniqueViolation is the sqlstate of a violated unique constraint.
+const uniqueViolation = "23505"
 
-	return tx.Commit()
+// IsUniqueViolation reports if err is the violation of a unique constraint.
+func IsUniqueViolation(err error) bool {
+	var pgErr pgdriver.Error
+	return errors.As(err, &pgErr) && pgErr.Field('C') == uniqueViolation
 }
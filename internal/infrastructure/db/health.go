@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"github.com/oaxacos/vitacare/pkg/health"
+)
+
+// PingCheck fails when the database can not be reached.
+func (db *DBRepository) PingCheck() health.Check {
+	return health.Check{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+	}
+}
+
+// MigrationCheck fails when the schema is behind the migrations embedded in
+// the binary.
+func (m *Migrator) MigrationCheck() health.Check {
+	return health.Check{
+		Name:     "migrations",
+		Critical: true,
+		Check:    m.CheckSchema,
+	}
+}
+
+// ReplicaCheck reports the read replica, the primary can still serve the
+// reads so it is not critical.
+func (db *DBRepository) ReplicaCheck() health.Check {
+	return health.Check{
+		Name: "database-replica",
+		Check: func(ctx context.Context) error {
+			return db.Replica.PingContext(ctx)
+		},
+	}
+}
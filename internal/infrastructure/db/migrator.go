@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/oaxacos/vitacare/migrations"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/uptrace/bun"
+)
+
+// migrationLockID is the advisory lock held while migrating so replicas
+// starting at the same time don't apply the same migration twice.
+const migrationLockID = 7310002
+
+var (
+	ErrNothingToRollback = errors.New("there is no migration to rollback")
+	ErrSchemaBehind      = errors.New("database schema is behind, run migrate up")
+)
+
+// MigrationStatus tells whether an embedded migration is applied.
+type MigrationStatus struct {
+	Version string
+	Name    string
+	Applied bool
+}
+
+// Migrator applies the embedded migrations, it uses the dbmate
+// schema_migrations table so both tools can be used on the same database.
+type Migrator struct {
+	db         *DBRepository
+	migrations []migrations.Migration
+}
+
+func NewMigrator(db *DBRepository) (*Migrator, error) {
+	loaded, err := migrations.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: loaded,
+	}, nil
+}
+
+// Up applies every pending migration and returns their versions.
+func (m *Migrator) Up(ctx context.Context) ([]string, error) {
+	var applied []string
+	err := m.withLock(ctx, func(conn bun.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if done[migration.Version] {
+				continue
+			}
+			logger.GetGlobalLogger().Infof("applying migration %s_%s", migration.Version, migration.Name)
+			err := m.run(ctx, conn, migration.Up, migration.UpTransaction, func(exec execer) error {
+				_, err := exec.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %s failed: %w", migration.Version, err)
+			}
+			applied = append(applied, migration.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last applied migration and returns its version.
+func (m *Migrator) Down(ctx context.Context) (string, error) {
+	var rolledBack string
+	err := m.withLock(ctx, func(conn bun.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if !done[migration.Version] {
+				continue
+			}
+			logger.GetGlobalLogger().Infof("rolling back migration %s_%s", migration.Version, migration.Name)
+			err := m.run(ctx, conn, migration.Down, migration.DownTransaction, func(exec execer) error {
+				_, err := exec.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("rollback of %s failed: %w", migration.Version, err)
+			}
+			rolledBack = migration.Version
+			return nil
+		}
+		return ErrNothingToRollback
+	})
+	return rolledBack, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status = append(status, MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: done[migration.Version],
+		})
+	}
+	return status, nil
+}
+
+// Pending returns the versions of the embedded migrations not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]string, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, migration := range status {
+		if !migration.Applied {
+			pending = append(pending, migration.Version)
+		}
+	}
+	return pending, nil
+}
+
+// CheckSchema fails when there are pending migrations, the server refuses to
+// start against an outdated schema.
+func (m *Migrator) CheckSchema(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations, first %s", ErrSchemaBehind, len(pending), pending[0])
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// run executes the statements and records the migration, the statements go
+// through database/sql directly so bun doesn't read ? as placeholders.
+func (m *Migrator) run(ctx context.Context, conn bun.Conn, statements string, transaction bool, record func(execer) error) error {
+	if !transaction {
+		if statements != "" {
+			if _, err := conn.Conn.ExecContext(ctx, statements); err != nil {
+				return err
+			}
+		}
+		return record(conn.Conn)
+	}
+
+	tx, err := conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if statements != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn bun.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// session level lock, it has to be taken and released on the same connection
+	if _, err := conn.Conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.Conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			logger.GetGlobalLogger().Errorf("error releasing migration lock: %s", err)
+		}
+	}()
+	return fn(conn)
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn bun.Conn) (map[string]bool, error) {
+	_, err := conn.Conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version varchar(128) PRIMARY KEY)")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		done[version] = true
+	}
+	return done, rows.Err()
+}
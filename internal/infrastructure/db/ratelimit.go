@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/ratelimit"
+)
+
+// RateLimitStore shares the rate limits between the instances of the api. The
+// row of the key is locked while the request is decided, a new key starts at
+// the epoch which Next treats as a key without requests.
+type RateLimitStore struct {
+	db *DBRepository
+}
+
+func NewRateLimitStore(db *DBRepository) *RateLimitStore {
+	return &RateLimitStore{db: db}
+}
+
+func (s *RateLimitStore) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	var result ratelimit.Result
+	err := s.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		db := s.db.Querier(ctx)
+		_, err := db.NewRaw(`INSERT INTO "rate_limits" ("key", "tat") VALUES (?, 'epoch') ON CONFLICT DO NOTHING`, key).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		var tat time.Time
+		err = db.NewRaw(`SELECT "tat" FROM "rate_limits" WHERE "key" = ? FOR UPDATE`, key).
+			Scan(ctx, &tat)
+		if err != nil {
+			return err
+		}
+		tat, result = ratelimit.Next(time.Now(), tat, limit)
+		_, err = db.NewRaw(`UPDATE "rate_limits" SET "tat" = ? WHERE "key" = ?`, tat, key).
+			Exec(ctx)
+		return err
+	})
+	return result, err
+}
+
+// Purge deletes the keys whose limit is full again.
+func (s *RateLimitStore) Purge(ctx context.Context) (int, error) {
+	res, err := s.db.Querier(ctx).NewRaw(`DELETE FROM "rate_limits" WHERE "tat" < ?`, time.Now()).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// RunPurge purges the store every interval until the context is cancelled.
+func (s *RateLimitStore) RunPurge(ctx context.Context, interval time.Duration) {
+	log := logger.GetContextLogger(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.Purge(ctx); err != nil {
+			log.Error(err)
+		}
+	}
+}
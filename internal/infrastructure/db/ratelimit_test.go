@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateLimitStore runs against the test database, it is skipped when the
+// database is not reachable.
+func TestRateLimitStore(t *testing.T) {
+	conf, err := config.NewConfig("test")
+	if err != nil {
+		t.Fatalf("error loading config %v", err)
+	}
+	repoDb, err := NewConnection(conf)
+	if err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+	defer repoDb.Close()
+
+	ctx := context.Background()
+	store := NewRateLimitStore(repoDb)
+	key := "test:" + uuid.NewString()
+	limit := ratelimit.Limit{Requests: 2, Window: time.Minute}
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		result, err := store.Allow(ctx, key, limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+	result, err := store.Allow(ctx, key, limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+
+	result, err = store.Allow(ctx, "test:"+uuid.NewString(), limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed, "the keys are counted apart")
+
+	_, err = repoDb.Querier(ctx).NewRaw(`DELETE FROM "rate_limits" WHERE "key" LIKE 'test:%'`).Exec(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// txContextKey carries the transaction of the unit of work, every repository
+// method called with that context runs in it.
+var txContextKey = "db_tx"
+
+// WithinTransaction runs fn in a transaction and commits it when fn returns
+// nil. When ctx already carries a transaction, fn runs in a savepoint so a
+// failure only rolls back its own changes. The transaction is rolled back if
+// ctx is cancelled.
+func (db *DBRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	var tx bun.Tx
+	if parent, ok := txFromContext(ctx); ok {
+		tx, err = parent.BeginTx(ctx, nil)
+	} else {
+		tx, err = db.DB.BeginTx(ctx, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txContextKey, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback: %w", rollbackErr))
+		}
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Querier returns the transaction carried by ctx or the primary database.
+func (db *DBRepository) Querier(ctx context.Context) bun.IDB {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	return db.DB
+}
+
+// Reader is like Querier but uses the replica outside of a transaction, only
+// for queries that can read data a few seconds old.
+func (db *DBRepository) Reader(ctx context.Context) bun.IDB {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	if db.Replica != nil {
+		return db.Replica
+	}
+	return db.DB
+}
+
+func txFromContext(ctx context.Context) (bun.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey).(bun.Tx)
+	return tx, ok
+}
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/service/account"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/pkg/i18n"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/middlewares"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+)
+
+type AccountController struct {
+	accountService *account.AccountService
+	userService    *user.UserService
+	config         *config.Config
+}
+
+const accountPrefix = "/users/me"
+
+func NewAccountController(deps Dependencies) *AccountController {
+	return &AccountController{
+		accountService: deps.Accounts,
+		userService:    deps.Users,
+		config:         deps.Config,
+	}
+}
+
+func (a *AccountController) Register(r chi.Router) {
+	r.Route(accountPrefix, func(r chi.Router) {
+		r.Use(middlewares.AuthMiddleware(a.config, a.userService))
+		r.Get("/export", a.handleExportData)
+		r.Post("/deletion", a.handleRequestDeletion)
+		r.Delete("/deletion", a.handleCancelDeletion)
+	})
+}
+
+func currentUserID(r *http.Request) uuid.UUID {
+	claims := utils.GetClaimsFromContext(r.Context())
+	if claims == nil {
+		return uuid.Nil
+	}
+	return claims.UserID
+}
+
+// @Router /users/me/export [get]
+// @Summary export user data
+// @Description Download all the data we hold about the logged user, use format=zip to get a zip bundle
+// @Tags users
+// @Security Token
+// @Param format query string false "json or zip"
+// @Success 200 {object} dto.UserExportDto
+func (a *AccountController) handleExportData(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	if userID == uuid.Nil {
+		response.RenderUnauthorized(w, r)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		response.RenderError(w, r, errInvalidFormat.WithArgs(format))
+		return
+	}
+
+	data, err := a.accountService.ExportUserData(r.Context(), userID)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	export := mapUserExport(data)
+
+	if format == "zip" {
+		err = writeExportZip(w, export)
+		if err != nil {
+			logger.GetContextLogger(r.Context()).Error(err)
+		}
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="vitacare-export.json"`)
+	response.RenderJson(w, export, http.StatusOK)
+}
+
+// @Router /users/me/deletion [post]
+// @Summary request account deletion
+// @Description The personal data of the logged user is anonymized once the grace period is over
+// @Tags users
+// @Security Token
+// @Success 202 {object} dto.AccountDeletionDto
+func (a *AccountController) handleRequestDeletion(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	if userID == uuid.Nil {
+		response.RenderUnauthorized(w, r)
+		return
+	}
+
+	scheduledFor, err := a.accountService.RequestDeletion(r.Context(), userID)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.AccountDeletionDto{
+		Message:      i18n.T(r.Context(), "deletion_requested"),
+		ScheduledFor: scheduledFor,
+	}
+	response.RenderJson(w, resp, http.StatusAccepted)
+}
+
+// @Router /users/me/deletion [delete]
+// @Summary cancel account deletion
+// @Description Cancel a pending account deletion request during the grace period
+// @Tags users
+// @Security Token
+// @Success 200 {object} string
+func (a *AccountController) handleCancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	if userID == uuid.Nil {
+		response.RenderUnauthorized(w, r)
+		return
+	}
+
+	err := a.accountService.CancelDeletion(r.Context(), userID)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	response.RenderJson(w, response.Envelop("message", i18n.T(r.Context(), "deletion_cancelled")), http.StatusOK)
+}
+
+func writeExportZip(w http.ResponseWriter, export dto.UserExportDto) error {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="vitacare-export.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	files := map[string]any{
+		"export.json":       export,
+		"user.json":         export.User,
+		"addresses.json":    export.Addresses,
+		"insurance.json":    export.Insurance,
+		"appointments.json": export.Appointments,
+		"sessions.json":     export.Sessions,
+	}
+	zw := zip.NewWriter(w)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func nullTimePtr(t time.Time, valid bool) *time.Time {
+	if !valid {
+		return nil
+	}
+	return &t
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func mapUserExport(data *model.UserDataExport) dto.UserExportDto {
+	user := data.User
+	export := dto.UserExportDto{
+		ExportedAt: data.ExportedAt,
+		User: dto.UserProfileDto{
+			ID:                  user.ID,
+			Email:               user.Email,
+			FirstName:           user.FirstName,
+			LastName:            user.LastName,
+			Role:                string(user.Rol),
+			Dni:                 user.DNI,
+			Phone:               user.Phone,
+			Language:            user.Language,
+			BirthDate:           nullTimePtr(user.Birthdate, !user.Birthdate.IsZero()),
+			IsActive:            user.IsActive,
+			CreatedAt:           user.CreatedAt,
+			UpdatedAt:           user.UpdateAt,
+			DeletionRequestedAt: nullTimePtr(user.DeletionRequestedAt.Time, user.DeletionRequestedAt.Valid),
+		},
+		Addresses:    make([]dto.AddressDto, 0, len(data.Addresses)),
+		Insurance:    make([]dto.InsuranceDto, 0, len(data.Insurance)),
+		Appointments: make([]dto.AppointmentDto, 0, len(data.Appointments)),
+		Sessions:     make([]dto.SessionExportDto, 0, len(data.Sessions)),
+	}
+	for _, address := range data.Addresses {
+		export.Addresses = append(export.Addresses, dto.AddressDto{
+			ID:           address.ID,
+			AddressLine1: address.AddressLine1,
+			AddressLine2: address.AddressLine2,
+			ZipCode:      address.ZipCode,
+			City:         address.City,
+			State:        address.State,
+			Country:      address.Country,
+		})
+	}
+	for _, insurance := range data.Insurance {
+		export.Insurance = append(export.Insurance, dto.InsuranceDto{
+			ID:                   insurance.ID,
+			Name:                 insurance.Name,
+			Institution:          insurance.Institution,
+			SocialSecurityNumber: insurance.SocialSecurityNumber,
+		})
+	}
+	for _, appointment := range data.Appointments {
+		export.Appointments = append(export.Appointments, dto.AppointmentDto{
+			ID:        appointment.ID,
+			Date:      appointment.Date,
+			DoctorID:  appointment.DoctorID,
+			ServiceID: nullUUIDPtr(appointment.ServiceID),
+			PackageID: nullUUIDPtr(appointment.PackageID),
+			Subtotal:  appointment.Subtotal,
+			Total:     appointment.Total,
+			PaymentAt: nullTimePtr(appointment.PaymentAt.Time, appointment.PaymentAt.Valid),
+			CreatedAt: appointment.CreatedAt,
+		})
+	}
+	// the token itself is a credential, we only export the session metadata
+	for _, session := range data.Sessions {
+		export.Sessions = append(export.Sessions, dto.SessionExportDto{
+			ID:        session.ID,
+			CreatedAt: session.CreatedAt,
+			ExpiredAt: session.ExpiredAt,
+		})
+	}
+	return export
+}
@@ -0,0 +1,121 @@
+package http_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	bootstrapTest "github.com/oaxacos/vitacare/internal/bootstrap/bootstraptest"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountController(t *testing.T) {
+	s := bootstrapTest.NewServer(t, nil)
+	ctx := context.Background()
+
+	send := func(method, path, token string, body any) *http.Response {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req, err := http.NewRequest(method, path, &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(utils.AuthorizationKey, "Bearer "+token)
+		}
+		return s.Do(req).Result()
+	}
+	credentials := map[string]any{"email": "laura@test.com", "password": "supersecret"}
+
+	resp := send(http.MethodPost, "/api/v1/users/auth/register", "", map[string]any{
+		"first_name":            "Laura",
+		"last_name":             "Diaz",
+		"email":                 "laura@test.com",
+		"password":              "supersecret",
+		"password_confirmation": "supersecret",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var loggedIn dto.UserLoggedInDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&loggedIn))
+	userID := loggedIn.User.ID
+	token := loggedIn.AccessToken
+	require.NoError(t, s.Store.Seed(ctx, &model.Address{ID: uuid.New(), UserID: userID, AddressLine1: "Calle 5", City: "Oaxaca", Country: "MX"}))
+
+	t.Run("export the user data", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/api/v1/users/me/export", "", nil).StatusCode)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/api/v1/users/me/export?format=xml", token, nil).StatusCode)
+
+		resp := send(http.MethodGet, "/api/v1/users/me/export", token, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var export dto.UserExportDto
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&export))
+		assert.Equal(t, "laura@test.com", export.User.Email)
+		assert.Len(t, export.Addresses, 1)
+		assert.Len(t, export.Sessions, 1)
+
+		resp = send(http.MethodGet, "/api/v1/users/me/export?format=zip", token, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+		var body bytes.Buffer
+		_, err := body.ReadFrom(resp.Body)
+		require.NoError(t, err)
+		archive, err := zip.NewReader(bytes.NewReader(body.Bytes()), int64(body.Len()))
+		require.NoError(t, err)
+		assert.Len(t, archive.File, 6)
+	})
+
+	t.Run("request and cancel the deletion", func(t *testing.T) {
+		resp := send(http.MethodPost, "/api/v1/users/me/deletion", token, nil)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		var deletion dto.AccountDeletionDto
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&deletion))
+		assert.WithinDuration(t, time.Now().Add(time.Duration(s.App.Config.Privacy.DeletionGracePeriod)*24*time.Hour), deletion.ScheduledFor, time.Minute)
+
+		session, err := s.App.Repositories.Token.GetByUserID(ctx, userID)
+		require.NoError(t, err)
+		assert.Nil(t, session, "the deletion request revokes the sessions")
+		assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/api/v1/users/me/deletion", token, nil).StatusCode)
+
+		assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/api/v1/users/me/deletion", token, nil).StatusCode)
+		assert.Equal(t, http.StatusConflict, send(http.MethodDelete, "/api/v1/users/me/deletion", token, nil).StatusCode)
+	})
+
+	t.Run("the anonymizer erases the expired accounts", func(t *testing.T) {
+		require.Equal(t, http.StatusAccepted, send(http.MethodPost, "/api/v1/users/me/deletion", token, nil).StatusCode)
+		n, err := s.App.Services.Account.AnonymizeExpiredAccounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n, "the grace period is not over")
+
+		user, err := s.App.Repositories.User.GetByID(ctx, userID)
+		require.NoError(t, err)
+		user.DeletionRequestedAt = sql.NullTime{Time: time.Now().AddDate(0, 0, -s.App.Config.Privacy.DeletionGracePeriod-1), Valid: true}
+		require.NoError(t, s.App.Repositories.User.Update(ctx, user))
+
+		n, err = s.App.Services.Account.AnonymizeExpiredAccounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		user, err = s.App.Repositories.User.GetByID(ctx, userID)
+		require.NoError(t, err)
+		assert.True(t, user.IsAnonymized())
+		assert.NotEqual(t, "laura@test.com", user.Email)
+		assert.Empty(t, user.FirstName)
+		addresses, err := s.App.Repositories.Address.GetByUserID(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, addresses)
+
+		assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/api/v1/users/auth/login", "", credentials).StatusCode)
+		assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/v1/users/me/export", token, nil).StatusCode, "the access token of an anonymized user is rejected")
+	})
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/service/apikey"
+	"github.com/oaxacos/vitacare/internal/domain/service/audit"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/pkg/middlewares"
+	"github.com/oaxacos/vitacare/pkg/response"
+)
+
+type AuditController struct {
+	auditService  *audit.AuditService
+	apiKeyService *apikey.APIKeyService
+	userService   *user.UserService
+	config        *config.Config
+	rateLimit     func(group string) func(next http.Handler) http.Handler
+}
+
+const (
+	auditPrefix       = "/audit"
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+func NewAuditController(deps Dependencies) *AuditController {
+	return &AuditController{
+		auditService:  deps.Audit,
+		apiKeyService: deps.APIKeys,
+		userService:   deps.Users,
+		config:        deps.Config,
+		rateLimit:     deps.RateLimit,
+	}
+}
+
+func (a *AuditController) Register(r chi.Router) {
+	r.Route(auditPrefix, func(r chi.Router) {
+		r.Use(
+			a.rateLimit("api-auth"),
+			middlewares.APIKeyMiddleware(a.config, a.apiKeyService, a.userService),
+			a.rateLimit("api"),
+			middlewares.ScopeMiddleware(a.config, model.ScopeAuditRead, model.AdminRole),
+		)
+		r.Get("/events", a.handleListEvents)
+		r.Get("/verify", a.handleVerifyChain)
+	})
+}
+
+// @Router /audit/events [get]
+// @Summary list audit events
+// @Description An admin can query the audit log of sensitive actions
+// @Tags audit
+// @Security Token
+// @Security ApiKey
+// @Param actor_id query string false "User who made the action"
+// @Param target_id query string false "User affected by the action"
+// @Param action query string false "Action, e.g. auth.login_failed"
+// @Param from query string false "RFC3339 start date"
+// @Param to query string false "RFC3339 end date"
+// @Param limit query int false "page size"
+// @Param offset query int false "page offset"
+// @Success 200 {object} dto.AuditEventListDto
+func (a *AuditController) handleListEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+
+	events, total, err := a.auditService.List(r.Context(), filter)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.AuditEventListDto{
+		Events: make([]dto.AuditEventDto, 0, len(events)),
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, dto.AuditEventDto{
+			ID:        event.ID,
+			Seq:       event.Seq,
+			Action:    string(event.Action),
+			ActorID:   nullUUIDPtr(event.ActorID),
+			TargetID:  nullUUIDPtr(event.TargetID),
+			IP:        event.IP,
+			RequestID: event.RequestID,
+			Metadata:  event.Metadata,
+			CreatedAt: event.CreatedAt,
+			Hash:      event.Hash,
+		})
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+// @Router /audit/verify [get]
+// @Summary verify the audit log
+// @Description Recompute the hash chain of the audit log to detect tampering
+// @Tags audit
+// @Security Token
+// @Security ApiKey
+// @Success 200 {object} dto.AuditVerificationDto
+func (a *AuditController) handleVerifyChain(w http.ResponseWriter, r *http.Request) {
+	result, err := a.auditService.VerifyChain(r.Context())
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.AuditVerificationDto{
+		Valid:    result.Valid,
+		Checked:  result.Checked,
+		BrokenAt: result.BrokenAt,
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+func parseAuditFilter(r *http.Request) (model.AuditFilter, error) {
+	query := r.URL.Query()
+	var filter model.AuditFilter
+	var err error
+
+	filter.Limit, err = queryInt(r, "limit", defaultAuditLimit)
+	if err != nil || filter.Limit <= 0 || filter.Limit > maxAuditLimit {
+		return filter, errInvalidLimit.WithArgs(maxAuditLimit)
+	}
+	filter.Offset, err = queryInt(r, "offset", 0)
+	if err != nil || filter.Offset < 0 {
+		return filter, errInvalidOffset
+	}
+	for key, dest := range map[string]*uuid.NullUUID{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		if value := query.Get(key); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return filter, errInvalidID.WithArgs(key, value)
+			}
+			*dest = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+	for key, dest := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(key); value != "" {
+			date, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errInvalidDateTime.WithArgs(key)
+			}
+			*dest = date
+		}
+	}
+	filter.Action = query.Get("action")
+	return filter, nil
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/service/account"
+	"github.com/oaxacos/vitacare/internal/domain/service/apikey"
+	"github.com/oaxacos/vitacare/internal/domain/service/audit"
+	"github.com/oaxacos/vitacare/internal/domain/service/token"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/internal/domain/service/webhook"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/validator"
+)
+
+// Dependencies are the services and the settings of the server the
+// controllers are built with.
+type Dependencies struct {
+	Config    *config.Config
+	Validator *validator.Validator
+	// Cookies are the attributes of the cookies the controllers set
+	Cookies response.CookieOptions
+	// RateLimit limits the requests of a group of routes
+	RateLimit func(group string) func(next http.Handler) http.Handler
+
+	Users    *user.UserService
+	Tokens   *token.TokenSvc
+	Accounts *account.AccountService
+	Audit    *audit.AuditService
+	APIKeys  *apikey.APIKeyService
+	Webhooks *webhook.WebhookService
+}
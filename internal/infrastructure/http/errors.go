@@ -0,0 +1,15 @@
+package http
+
+import "github.com/oaxacos/vitacare/pkg/apperror"
+
+// errors of the request parameters, the messages are translated by the code
+// in the i18n catalog
+var (
+	errInvalidID       = apperror.Validation("invalid_id", "invalid %s: %s")
+	errInvalidUserID   = apperror.Validation("invalid_user_id", "invalid user id: %s")
+	errInvalidLimit    = apperror.Validation("invalid_limit", "limit must be between 1 and %d")
+	errInvalidOffset   = apperror.Validation("invalid_offset", "offset must be a positive number")
+	errInvalidDate     = apperror.Validation("invalid_date", "%s must be a YYYY-MM-DD date")
+	errInvalidDateTime = apperror.Validation("invalid_datetime", "%s must be a RFC3339 date")
+	errInvalidFormat   = apperror.Validation("invalid_format", "invalid format: %s")
+)
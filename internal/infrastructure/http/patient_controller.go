@@ -0,0 +1,163 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/service/account"
+	"github.com/oaxacos/vitacare/internal/domain/service/apikey"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/pkg/i18n"
+	"github.com/oaxacos/vitacare/pkg/middlewares"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/oaxacos/vitacare/pkg/validator"
+)
+
+type PatientController struct {
+	userService    *user.UserService
+	accountService *account.AccountService
+	apiKeyService  *apikey.APIKeyService
+	validator      *validator.Validator
+	config         *config.Config
+	rateLimit      func(group string) func(next http.Handler) http.Handler
+}
+
+const (
+	patientPrefix       = "/patients"
+	defaultPatientLimit = 20
+	maxPatientLimit     = 100
+)
+
+func NewPatientController(deps Dependencies) *PatientController {
+	return &PatientController{
+		userService:    deps.Users,
+		accountService: deps.Accounts,
+		apiKeyService:  deps.APIKeys,
+		validator:      deps.Validator,
+		config:         deps.Config,
+		rateLimit:      deps.RateLimit,
+	}
+}
+
+func (p *PatientController) Register(r chi.Router) {
+	r.Route(patientPrefix, func(r chi.Router) {
+		// the addresses are limited before the key is checked
+		r.Use(p.rateLimit("api-auth"), middlewares.APIKeyMiddleware(p.config, p.apiKeyService, p.userService), p.rateLimit("api"))
+		r.With(middlewares.ScopeMiddleware(p.config, model.ScopePatientsRead, model.AdminRole, model.DoctorRole, model.SecretaryRole)).
+			Get("/", p.handleListPatients)
+		r.With(middlewares.ScopeMiddleware(p.config, model.ScopePatientsWrite, model.AdminRole, model.DoctorRole)).
+			Patch("/{id}/deceased", p.handleMarkDeceased)
+	})
+}
+
+// @Router /patients/ [get]
+// @Summary list active patients
+// @Description List the active patients, deceased and deleted patients are excluded
+// @Tags patients
+// @Security Token
+// @Security ApiKey
+// @Param limit query int false "page size"
+// @Param offset query int false "page offset"
+// @Success 200 {object} dto.PatientListDto
+func (p *PatientController) handleListPatients(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultPatientLimit)
+	if err != nil || limit <= 0 || limit > maxPatientLimit {
+		response.RenderError(w, r, errInvalidLimit.WithArgs(maxPatientLimit))
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		response.RenderError(w, r, errInvalidOffset)
+		return
+	}
+
+	patients, total, err := p.userService.ListActivePatients(r.Context(), limit, offset)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.PatientListDto{
+		Patients: make([]dto.PatientDto, 0, len(patients)),
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, patient := range patients {
+		resp.Patients = append(resp.Patients, dto.PatientDto{
+			ID:        patient.ID,
+			FirstName: patient.FirstName,
+			LastName:  patient.LastName,
+			Email:     patient.Email,
+			Phone:     patient.Phone,
+		})
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+// @Router /patients/{id}/deceased [patch]
+// @Summary record the death of a patient
+// @Description An admin or doctor records the date of death, future appointments are cancelled and sessions revoked
+// @Tags patients
+// @Security Token
+// @Security ApiKey
+// @Param id path string true "User ID"
+// @Param data body dto.MarkDeceasedDto true "Date of death"
+// @Success 200 {object} dto.MarkDeceasedResponse
+func (p *PatientController) handleMarkDeceased(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actorID := utils.GetActorID(ctx)
+	if actorID == uuid.Nil {
+		response.RenderUnauthorized(w, r)
+		return
+	}
+
+	patientID := chi.URLParam(r, "id")
+	patientIDParsed, err := uuid.Parse(patientID)
+	if err != nil {
+		response.RenderError(w, r, errInvalidUserID.WithArgs(patientID))
+		return
+	}
+
+	var data dto.MarkDeceasedDto
+	err = utils.ReadFromRequest(r, &data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	err = p.validator.ValidateStruct(r.Context(), data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	deceasedAt, err := time.Parse(time.DateOnly, data.DeceasedAt)
+	if err != nil {
+		response.RenderError(w, r, errInvalidDate.WithArgs("deceased_at"))
+		return
+	}
+
+	cancelled, err := p.accountService.MarkDeceased(ctx, actorID, patientIDParsed, deceasedAt)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.MarkDeceasedResponse{
+		Message:               i18n.T(ctx, "patient_marked_deceased"),
+		CancelledAppointments: cancelled,
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+func queryInt(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,104 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	bootstrapTest "github.com/oaxacos/vitacare/internal/bootstrap/bootstraptest"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	pkgResponse "github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatientController(t *testing.T) {
+	s := bootstrapTest.NewServer(t, nil)
+	admin, adminToken := seedAdmin(t, s)
+
+	send := func(method, path, token string, body any) *http.Response {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req, err := http.NewRequest(method, path, &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(utils.AuthorizationKey, "Bearer "+token)
+		return s.Do(req).Result()
+	}
+	deceased := map[string]any{"deceased_at": time.Now().AddDate(0, 0, -1).Format(time.DateOnly)}
+
+	t.Run("only patients are marked as deceased", func(t *testing.T) {
+		doctor := model.NewUser(dto.UserDto{Email: "doctor@test.com", FirstName: "Luis", LastName: "Perez"}, model.DoctorRole)
+		doctor.Password = nil
+		require.NoError(t, s.Store.Seed(context.Background(), doctor))
+
+		resp := send(http.MethodPatch, "/api/v1/patients/"+doctor.ID.String()+"/deceased", adminToken, deceased)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		var problem pkgResponse.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, "user_not_patient", problem.Code)
+	})
+
+	t.Run("the access tokens of a deceased patient are rejected", func(t *testing.T) {
+		resp := send(http.MethodPost, "/api/v1/users/auth/register", "", map[string]any{
+			"first_name":            "Rosa",
+			"last_name":             "Mendez",
+			"email":                 "rosa@test.com",
+			"password":              "supersecret",
+			"password_confirmation": "supersecret",
+		})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var loggedIn dto.UserLoggedInDto
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&loggedIn))
+		assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/v1/users/me/export", loggedIn.AccessToken, nil).StatusCode)
+
+		resp = send(http.MethodPatch, "/api/v1/patients/"+loggedIn.User.ID.String()+"/deceased", adminToken, deceased)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = send(http.MethodGet, "/api/v1/users/me/export", loggedIn.AccessToken, nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		var problem pkgResponse.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, "user_inactive", problem.Code)
+	})
+
+	t.Run("the cancelled appointments are published", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := s.App.Services.Webhook.CreateSubscription(ctx, admin.ID, "https://lab.test/hooks", "",
+			[]model.WebhookEvent{model.WebhookAppointmentCancelled})
+		require.NoError(t, err)
+
+		patient := model.NewPatientUser(dto.UserDto{Email: "carmen@test.com", FirstName: "Carmen", LastName: "Lopez"})
+		patient.Password = nil
+		appointment := &model.Appointment{
+			ID:        uuid.New(),
+			Date:      time.Now().AddDate(0, 0, 2),
+			PatientID: patient.ID,
+			DoctorID:  admin.ID,
+			Status:    model.AppointmentScheduled,
+		}
+		require.NoError(t, s.Store.Seed(ctx, patient, appointment))
+
+		resp := send(http.MethodPatch, "/api/v1/patients/"+patient.ID.String()+"/deceased", adminToken, deceased)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		deliveries, _, err := s.App.Services.Webhook.ListDeliveries(ctx, model.WebhookDeliveryFilter{Event: string(model.WebhookAppointmentCancelled), Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		var payload struct {
+			Data model.AppointmentCancelledData `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+		assert.Equal(t, appointment.ID, payload.Data.ID)
+		assert.Equal(t, patient.ID, payload.Data.PatientID)
+		assert.Equal(t, model.CancellationPatientDeceased, payload.Data.Reason)
+	})
+}
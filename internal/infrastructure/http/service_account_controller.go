@@ -0,0 +1,263 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/service/apikey"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/pkg/middlewares"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/oaxacos/vitacare/pkg/validator"
+)
+
+type ServiceAccountController struct {
+	apiKeyService *apikey.APIKeyService
+	userService   *user.UserService
+	validator     *validator.Validator
+	config        *config.Config
+}
+
+const serviceAccountPrefix = "/service-accounts"
+
+func NewServiceAccountController(deps Dependencies) *ServiceAccountController {
+	return &ServiceAccountController{
+		apiKeyService: deps.APIKeys,
+		userService:   deps.Users,
+		validator:     deps.Validator,
+		config:        deps.Config,
+	}
+}
+
+func (s *ServiceAccountController) Register(r chi.Router) {
+	r.Route(serviceAccountPrefix, func(r chi.Router) {
+		r.Use(middlewares.AuthMiddleware(s.config, s.userService), middlewares.AdminMiddleware(s.config))
+		r.Post("/", s.handleCreateServiceAccount)
+		r.Get("/", s.handleListServiceAccounts)
+		r.Post("/{id}/keys", s.handleCreateKey)
+		r.Get("/{id}/keys", s.handleListKeys)
+		r.Post("/{id}/keys/{keyID}/rotate", s.handleRotateKey)
+		r.Delete("/{id}/keys/{keyID}", s.handleRevokeKey)
+	})
+}
+
+// @Router /service-accounts/ [post]
+// @Summary create a service account
+// @Description An admin creates the account of an integration, it authenticates with api keys
+// @Tags service-accounts
+// @Security Token
+// @Param data body dto.CreateServiceAccountDto true "Service account"
+// @Success 201 {object} dto.ServiceAccountDto
+func (s *ServiceAccountController) handleCreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var data dto.CreateServiceAccountDto
+	err := utils.ReadFromRequest(r, &data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	err = s.validator.ValidateStruct(r.Context(), data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+
+	account, err := s.apiKeyService.CreateServiceAccount(r.Context(), currentUserID(r), data.Name, data.Description)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	response.RenderJson(w, mapServiceAccount(account), http.StatusCreated)
+}
+
+// @Router /service-accounts/ [get]
+// @Summary list service accounts
+// @Tags service-accounts
+// @Security Token
+// @Success 200 {object} dto.ServiceAccountListDto
+func (s *ServiceAccountController) handleListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := s.apiKeyService.ListServiceAccounts(r.Context())
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.ServiceAccountListDto{
+		ServiceAccounts: make([]dto.ServiceAccountDto, 0, len(accounts)),
+	}
+	for _, account := range accounts {
+		resp.ServiceAccounts = append(resp.ServiceAccounts, mapServiceAccount(account))
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+// @Router /service-accounts/{id}/keys [post]
+// @Summary create an api key
+// @Description The key is only returned in this response, send it in the X-API-Key header
+// @Tags service-accounts
+// @Security Token
+// @Param id path string true "Service account ID"
+// @Param data body dto.CreateAPIKeyDto true "Api key"
+// @Success 201 {object} dto.CreatedAPIKeyDto
+func (s *ServiceAccountController) handleCreateKey(w http.ResponseWriter, r *http.Request) {
+	accountID, err := pathID(r, "id")
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	var data dto.CreateAPIKeyDto
+	err = utils.ReadFromRequest(r, &data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	err = s.validator.ValidateStruct(r.Context(), data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	opts := apikey.KeyOptions{
+		Name:      data.Name,
+		RateLimit: data.RateLimit,
+	}
+	for _, scope := range data.Scopes {
+		opts.Scopes = append(opts.Scopes, model.Scope(scope))
+	}
+	if data.ExpiresAt != "" {
+		opts.ExpiresAt, err = time.Parse(time.RFC3339, data.ExpiresAt)
+		if err != nil {
+			response.RenderError(w, r, errInvalidDateTime.WithArgs("expires_at"))
+			return
+		}
+	}
+
+	key, value, err := s.apiKeyService.CreateKey(r.Context(), accountID, opts)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	response.RenderJson(w, dto.CreatedAPIKeyDto{APIKeyDto: mapAPIKey(key), Key: value}, http.StatusCreated)
+}
+
+// @Router /service-accounts/{id}/keys [get]
+// @Summary list the api keys of a service account
+// @Tags service-accounts
+// @Security Token
+// @Param id path string true "Service account ID"
+// @Success 200 {object} dto.APIKeyListDto
+func (s *ServiceAccountController) handleListKeys(w http.ResponseWriter, r *http.Request) {
+	accountID, err := pathID(r, "id")
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	keys, err := s.apiKeyService.ListKeys(r.Context(), accountID)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.APIKeyListDto{
+		Keys: make([]dto.APIKeyDto, 0, len(keys)),
+	}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, mapAPIKey(key))
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+// @Router /service-accounts/{id}/keys/{keyID}/rotate [post]
+// @Summary rotate an api key
+// @Description Creates a new key with the same settings, the old one keeps working for 24 hours
+// @Tags service-accounts
+// @Security Token
+// @Param id path string true "Service account ID"
+// @Param keyID path string true "Api key ID"
+// @Success 201 {object} dto.CreatedAPIKeyDto
+func (s *ServiceAccountController) handleRotateKey(w http.ResponseWriter, r *http.Request) {
+	accountID, keyID, err := keyPathIDs(r)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	key, value, err := s.apiKeyService.RotateKey(r.Context(), accountID, keyID)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	response.RenderJson(w, dto.CreatedAPIKeyDto{APIKeyDto: mapAPIKey(key), Key: value}, http.StatusCreated)
+}
+
+// @Router /service-accounts/{id}/keys/{keyID} [delete]
+// @Summary revoke an api key
+// @Description The key stops working immediately
+// @Tags service-accounts
+// @Security Token
+// @Param id path string true "Service account ID"
+// @Param keyID path string true "Api key ID"
+// @Success 204
+func (s *ServiceAccountController) handleRevokeKey(w http.ResponseWriter, r *http.Request) {
+	accountID, keyID, err := keyPathIDs(r)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	err = s.apiKeyService.RevokeKey(r.Context(), accountID, keyID)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pathID(r *http.Request, key string) (uuid.UUID, error) {
+	value := chi.URLParam(r, key)
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, errInvalidID.WithArgs(key, value)
+	}
+	return id, nil
+}
+
+func keyPathIDs(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	accountID, err := pathID(r, "id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	keyID, err := pathID(r, "keyID")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return accountID, keyID, nil
+}
+
+func mapServiceAccount(account *model.ServiceAccount) dto.ServiceAccountDto {
+	return dto.ServiceAccountDto{
+		ID:          account.ID,
+		Name:        account.Name,
+		Description: account.Description,
+		CreatedBy:   account.CreatedBy,
+		CreatedAt:   account.CreatedAt,
+	}
+}
+
+func mapAPIKey(key *model.APIKey) dto.APIKeyDto {
+	resp := dto.APIKeyDto{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     make([]string, 0, len(key.Scopes)),
+		RateLimit:  key.RateLimit,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  nullTimePtr(key.ExpiresAt.Time, key.ExpiresAt.Valid),
+		RevokedAt:  nullTimePtr(key.RevokedAt.Time, key.RevokedAt.Valid),
+		LastUsedAt: nullTimePtr(key.LastUsedAt.Time, key.LastUsedAt.Valid),
+	}
+	for _, scope := range key.Scopes {
+		resp.Scopes = append(resp.Scopes, string(scope))
+	}
+	return resp
+}
@@ -0,0 +1,123 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	bootstrapTest "github.com/oaxacos/vitacare/internal/bootstrap/bootstraptest"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedAdmin adds an admin to the store and returns its access token.
+func seedAdmin(t *testing.T, s *bootstrapTest.Server) (*model.User, string) {
+	t.Helper()
+	admin := model.NewUser(dto.UserDto{Email: "admin@test.com", FirstName: "Ada", LastName: "Admin"}, model.AdminRole)
+	admin.Password = nil
+	require.NoError(t, s.Store.Seed(context.Background(), admin))
+	token, err := utils.GenerateAccessToken(admin, time.Hour, []byte(s.App.Config.Token.PrivateKeyAccessToken))
+	require.NoError(t, err)
+	return admin, token
+}
+
+func TestServiceAccountController(t *testing.T) {
+	s := bootstrapTest.NewServer(t, nil)
+	admin, adminToken := seedAdmin(t, s)
+
+	send := func(method, path string, body any, header, value string) *http.Response {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req, err := http.NewRequest(method, path, &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, value)
+		return s.Do(req).Result()
+	}
+	asAdmin := func(method, path string, body any) *http.Response {
+		return send(method, path, body, utils.AuthorizationKey, "Bearer "+adminToken)
+	}
+
+	resp := asAdmin(http.MethodPost, "/api/v1/service-accounts/", map[string]any{"name": "lab"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var account dto.ServiceAccountDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&account))
+	assert.Equal(t, admin.ID, account.CreatedBy)
+	resp = asAdmin(http.MethodPost, "/api/v1/service-accounts/", map[string]any{"name": "lab"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "the name is unique")
+
+	keysPath := "/api/v1/service-accounts/" + account.ID.String() + "/keys"
+	resp = asAdmin(http.MethodPost, keysPath, map[string]any{"name": "results", "scopes": []string{"unknown"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = asAdmin(http.MethodPost, keysPath, map[string]any{"name": "results", "scopes": []string{"patients:read"}, "rate_limit": 5})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created dto.CreatedAPIKeyDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Contains(t, created.Key, model.APIKeyPrefix+created.Prefix+".")
+
+	withKey := func(method, path, key string) *http.Response {
+		return send(method, path, nil, utils.APIKeyHeader, key)
+	}
+
+	t.Run("the key is limited to its scopes", func(t *testing.T) {
+		resp := withKey(http.MethodGet, "/api/v1/patients/", created.Key)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "5;w=60", resp.Header.Get("RateLimit-Policy"), "the key has its own rate limit")
+		assert.Equal(t, http.StatusForbidden, withKey(http.MethodGet, "/api/v1/audit/events", created.Key).StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/api/v1/service-accounts/", created.Key).StatusCode, "the admin routes need a user")
+	})
+
+	t.Run("a wrong secret is rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/api/v1/patients/", created.Key+"x").StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/api/v1/patients/", "not a key").StatusCode)
+	})
+
+	t.Run("rotate and revoke", func(t *testing.T) {
+		resp := asAdmin(http.MethodPost, keysPath+"/"+created.ID.String()+"/rotate", nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var rotated dto.CreatedAPIKeyDto
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rotated))
+		assert.Equal(t, created.Scopes, rotated.Scopes)
+		assert.NotEqual(t, created.Key, rotated.Key)
+		assert.Equal(t, http.StatusOK, withKey(http.MethodGet, "/api/v1/patients/", created.Key).StatusCode, "the old key works during the grace period")
+
+		resp = asAdmin(http.MethodDelete, keysPath+"/"+created.ID.String(), nil)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/api/v1/patients/", created.Key).StatusCode)
+
+		resp = asAdmin(http.MethodGet, keysPath, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var keys dto.APIKeyListDto
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+		require.Len(t, keys.Keys, 2)
+		assert.NotNil(t, keys.Keys[0].RevokedAt)
+		assert.NotNil(t, keys.Keys[0].LastUsedAt)
+	})
+}
+
+func TestAPIKeyGuessesAreLimited(t *testing.T) {
+	conf, err := config.NewConfig("test")
+	require.NoError(t, err)
+	conf.RateLimit.Groups["api-auth"] = config.RateLimitGroup{Requests: 2, Window: 60, Key: "ip"}
+	s := bootstrapTest.NewServer(t, conf)
+
+	guess := func() int {
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/patients/", nil)
+		require.NoError(t, err)
+		req.Header.Set(utils.APIKeyHeader, "vc_guessed.secret")
+		return s.Do(req).Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, guess())
+	assert.Equal(t, http.StatusUnauthorized, guess())
+	assert.Equal(t, http.StatusTooManyRequests, guess(), "the failed checks count against the address")
+}
@@ -2,7 +2,6 @@ package http
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -11,10 +10,10 @@ import (
 	"github.com/oaxacos/vitacare/internal/domain/service/user"
 	"github.com/oaxacos/vitacare/pkg/logger"
 	"github.com/oaxacos/vitacare/pkg/middlewares"
-	"github.com/oaxacos/vitacare/pkg/server"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/pkg/i18n"
 	"github.com/oaxacos/vitacare/pkg/response"
 	"github.com/oaxacos/vitacare/pkg/utils"
 	"github.com/oaxacos/vitacare/pkg/validator"
@@ -23,45 +22,52 @@ import (
 type UserController struct {
 	userService  *user.UserService
 	tokenService *token.TokenSvc
-	c            *chi.Mux
 	Config       *config.Config
 	validator    *validator.Validator
+	cookies      response.CookieOptions
+	rateLimit    func(group string) func(next http.Handler) http.Handler
 }
 
-const prefix = "/api/v0/users"
+const prefix = "/users"
 
-func NewUserController(s *server.Server, userSvc *user.UserService, tokenSvc *token.TokenSvc, validator *validator.Validator) {
-	userController := &UserController{
-		c:            s.Mux,
-		Config:       s.Config,
-		userService:  userSvc,
-		tokenService: tokenSvc,
-		validator:    validator,
+// authMaxBodySize limits the bodies of the auth routes, they only carry
+// credentials.
+const authMaxBodySize = 8 << 10
+
+func NewUserController(deps Dependencies) *UserController {
+	return &UserController{
+		Config:       deps.Config,
+		userService:  deps.Users,
+		tokenService: deps.Tokens,
+		validator:    deps.Validator,
+		cookies:      deps.Cookies,
+		rateLimit:    deps.RateLimit,
 	}
+}
 
-	userController.c.Route(prefix, func(r chi.Router) {
-		// authPath := fmt.Sprintf("%s/auth", prefix)
-		// fmt.Printf("auth path: %s", authPath)
+func (u *UserController) Register(r chi.Router) {
+	r.Route(prefix, func(r chi.Router) {
 		r.Route("/auth", func(r chi.Router) {
+			r.Use(u.rateLimit("auth"), middlewares.MaxBodySize(authMaxBodySize))
 
-			r.Post("/register", userController.handleRegisterUser)
-			r.Post("/login", userController.handleLogin)
-			r.Post("/renew", userController.handleRenewToken)
+			r.Post("/register", u.handleRegisterUser)
+			r.Post("/login", u.handleLogin)
+			r.With(middlewares.CSRFMiddleware(u.Config)).Post("/renew", u.handleRenewToken)
 			r.Group(func(r chi.Router) {
-				r.Use(middlewares.AuthMiddleware(s.Config))
-				r.Put("/logout", userController.handleLogout)
+				r.Use(middlewares.AuthMiddleware(u.Config, u.userService))
+				r.Put("/logout", u.handleLogout)
 			})
 
 		})
 		r.Group(func(r chi.Router) {
-			r.Use(middlewares.AuthMiddleware(s.Config), middlewares.AdminMiddleware(s.Config))
-			r.Patch("/{id}/role", userController.handleUpdateUserRole)
-			r.Patch("/", userController.handleUpdateUser)
+			r.Use(middlewares.AuthMiddleware(u.Config, u.userService), middlewares.AdminMiddleware(u.Config))
+			r.Patch("/{id}/role", u.handleUpdateUserRole)
+			r.Patch("/", u.handleUpdateUser)
 		})
 	})
 }
 
-// @Router /api/v0/users/auth/register [post]
+// @Router /users/auth/register [post]
 // @Summary Register a new user
 // @Description Register a new user in the system
 // @Tags users
@@ -73,30 +79,30 @@ func (u *UserController) handleRegisterUser(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	err := utils.ReadFromRequest(r, &userData)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
 
-	err = u.validator.ValidateStruct(userData)
+	err = u.validator.ValidateStruct(r.Context(), userData)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
 	err = u.userService.ExistUser(r.Context(), userData.Email)
 	if err != nil {
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
 	// create user
 	newUser, err := u.userService.CreateUser(r.Context(), userData)
 	if err != nil {
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
 	// create refresh token and access token
 	accessToken, refreshToken, err := u.tokenService.GenerateToken(ctx, newUser)
 	if err != nil {
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
 	dataResponse := dto.UserLoggedInDto{
@@ -107,13 +113,29 @@ func (u *UserController) handleRegisterUser(w http.ResponseWriter, r *http.Reque
 			FirstName: newUser.FirstName,
 			LastName:  newUser.LastName,
 			Email:     newUser.Email,
+			Language:  newUser.Language,
 		},
 	}
-	response.SetRefreshTokenCookie(w, refreshToken)
+	if err := u.setSessionCookies(w, refreshToken); err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
 	response.RenderJson(w, dataResponse, http.StatusCreated)
 }
 
-// @Router /api/v0/users/auth/login [post]
+// setSessionCookies sets the refresh token cookie and a new csrf token that
+// protects it.
+func (u *UserController) setSessionCookies(w http.ResponseWriter, refreshToken string) error {
+	csrfToken, err := utils.GenerateCSRFToken()
+	if err != nil {
+		return err
+	}
+	response.SetRefreshTokenCookie(w, refreshToken, u.cookies)
+	response.SetCSRFCookie(w, csrfToken, u.cookies)
+	return nil
+}
+
+// @Router /users/auth/login [post]
 // @Summary login a user
 // @Description login a user and set a cookie with the refresh token
 // @Tags users
@@ -123,24 +145,24 @@ func (u *UserController) handleLogin(w http.ResponseWriter, r *http.Request) {
 	var loginData dto.UserLoginDto
 	err := utils.ReadFromRequest(r, &loginData)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
-	err = u.validator.ValidateStruct(loginData)
+	err = u.validator.ValidateStruct(r.Context(), loginData)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
 	userWithCredentials, err := u.userService.LoginUser(r.Context(), loginData)
 	if err != nil {
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
 	ctx := r.Context()
 	// create refresh token and access token
 	accessToken, refreshToken, err := u.tokenService.GenerateToken(ctx, userWithCredentials)
 	if err != nil {
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
 	dataResponse := dto.UserLoggedInDto{
@@ -150,15 +172,19 @@ func (u *UserController) handleLogin(w http.ResponseWriter, r *http.Request) {
 			FirstName: userWithCredentials.FirstName,
 			LastName:  userWithCredentials.LastName,
 			Email:     userWithCredentials.Email,
+			Language:  userWithCredentials.Language,
 		},
 	}
-	response.SetRefreshTokenCookie(w, refreshToken)
+	if err := u.setSessionCookies(w, refreshToken); err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
 	response.WriteJsonResponse(w, dataResponse, http.StatusOK)
 }
 
-// @Router /api/v0/users/auth/renew [post]
+// @Router /users/auth/renew [post]
 // @Summary renew access token
-// @Description renew access token with refresh token
+// @Description renew access token with the refresh token of the body or of the cookie, the cookie requires the X-CSRF-Token header
 // @Tags users
 // @Success 200 {object} dto.UserDto
 // @Param user body dto.TokenRefreshRequest true "User data"
@@ -166,38 +192,54 @@ func (u *UserController) handleRenewToken(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 	log := logger.GetContextLogger(ctx)
 	var refreshToken dto.TokenRefreshRequest
-	err := utils.ReadFromRequest(r, &refreshToken)
-	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
-		return
+	var err error
+	// the browsers send the cookie, the csrf middleware already checked it
+	if cookie := utils.GetRefreshTokenFromCookie(r); cookie != "" && r.ContentLength == 0 {
+		refreshToken.RefreshToken = cookie
+	} else {
+		err = utils.ReadFromRequest(r, &refreshToken)
+		if err != nil {
+			response.RenderError(w, r, err)
+			return
+		}
 	}
 
-	err = u.validator.ValidateStruct(refreshToken)
+	err = u.validator.ValidateStruct(r.Context(), refreshToken)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
 	refreshTokenModel, err := u.tokenService.ValidateRefreshToken(ctx, refreshToken.RefreshToken)
 	if err != nil {
 		log.Error(err)
 		if errors.Is(err, token.ErrInvalidToken) {
-			err := u.tokenService.DeleteRefreshToken(refreshToken.RefreshToken)
+			err := u.tokenService.DeleteRefreshToken(ctx, refreshToken.RefreshToken)
 			log.Error(err)
 			if err != nil {
 
-				response.RenderFatalError(w, err)
+				response.RenderError(w, r, err)
 				return
 			}
-			response.RenderUnauthorized(w)
+			response.RenderUnauthorized(w, r)
 			return
 		}
-		response.RenderUnauthorized(w)
+		response.RenderUnauthorized(w, r)
 		return
 	}
 	userInDB, err := u.userService.GetByID(ctx, refreshTokenModel.UserID)
 	if err != nil {
 		log.Error(err)
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
+		return
+	}
+	if !userInDB.CanAuthenticate() {
+		log.Infof("user %s can not renew the session, account is inactive", userInDB.ID)
+		err = u.tokenService.DeleteRefreshToken(ctx, refreshToken.RefreshToken)
+		if err != nil {
+			log.Error(err)
+		}
+		response.DeleteRefreshTokenCookie(w, u.cookies)
+		response.RenderUnauthorized(w, r)
 		return
 	}
 	newAccessToken, err := u.tokenService.GenerateAccessToken(ctx, userInDB)
@@ -209,13 +251,14 @@ func (u *UserController) handleRenewToken(w http.ResponseWriter, r *http.Request
 			FirstName: userInDB.FirstName,
 			LastName:  userInDB.LastName,
 			Email:     userInDB.Email,
+			Language:  userInDB.Language,
 		},
 	}
-	response.SetRefreshTokenCookie(w, refreshToken.RefreshToken)
+	response.SetRefreshTokenCookie(w, refreshToken.RefreshToken, u.cookies)
 	response.WriteJsonResponse(w, resp, http.StatusOK)
 }
 
-// @Router /api/v0/users/auth/logout [put]
+// @Router /users/auth/logout [put]
 // @Summary logout a user
 // @Description logout a user and delete the refresh token
 // @Tags users
@@ -225,20 +268,20 @@ func (u *UserController) handleLogout(w http.ResponseWriter, r *http.Request) {
 	log := logger.GetContextLogger(ctx)
 	claims := utils.GetClaimsFromContext(ctx)
 	if claims == nil {
-		response.RenderUnauthorized(w)
+		response.RenderUnauthorized(w, r)
 		return
 	}
-	err := u.tokenService.DeleteRefreshTokenByUser(claims.UserID)
+	err := u.tokenService.DeleteRefreshTokenByUser(ctx, claims.UserID)
 	if err != nil {
 		log.Error(err)
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
-	response.DeleteRefreshTokenCookie(w)
-	response.RenderJson(w, response.Envelop("message", "success"), http.StatusOK)
+	response.DeleteRefreshTokenCookie(w, u.cookies)
+	response.RenderJson(w, response.Envelop("message", i18n.T(ctx, "logged_out")), http.StatusOK)
 }
 
-// @Router /api/v0/users/{id}/role [patch]
+// @Router /users/{id}/role [patch]
 // @Summary update user role
 // @Security <YourTypeOfKey>
 // @Description An admin can update the role of a user
@@ -255,29 +298,29 @@ func (u *UserController) handleUpdateUserRole(w http.ResponseWriter, r *http.Req
 
 	userIdParsed, err := uuid.Parse(userIDToUpdate)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, fmt.Sprintf("invalid user id: %s", userIDToUpdate))
+		response.RenderError(w, r, errInvalidUserID.WithArgs(userIDToUpdate))
 		return
 	}
 
 	err = utils.ReadFromRequest(r, &updateUserRole)
 
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
 
 	err = u.userService.UpdateUserRole(ctx, userIdParsed, updateUserRole.Role)
 	if err != nil {
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
-	resp := response.Envelop("message", "user role updated")
+	resp := response.Envelop("message", i18n.T(ctx, "user_role_updated"))
 
 	response.RenderJson(w, resp, http.StatusOK)
 
 }
 
-// @Router /api/v0/users/ [patch]
+// @Router /users/ [patch]
 // @Summary update user profile
 // @Security <YourTypeOfKey>
 // @Description Any user can update his profile, first name, last name, dni, phone and birthdate
@@ -289,40 +332,40 @@ func (u *UserController) handleUpdateUser(w http.ResponseWriter, r *http.Request
 	claims := utils.GetClaimsFromContext(r.Context())
 
 	if claims == nil {
-		response.RenderUnauthorized(w)
+		response.RenderUnauthorized(w, r)
 		return
 	}
 
 	userID := claims.UserID
 
 	if userID == uuid.Nil {
-		response.RenderUnauthorized(w)
+		response.RenderUnauthorized(w, r)
 		return
 	}
 
 	var updateUser dto.UpdateUserDto
 	err := utils.ReadFromRequest(r, &updateUser)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
 
-	err = u.validator.ValidateStruct(updateUser)
+	err = u.validator.ValidateStruct(r.Context(), updateUser)
 	if err != nil {
-		response.RenderError(w, http.StatusBadRequest, err.Error())
+		response.RenderError(w, r, err)
 		return
 	}
 
 	isEmpty := utils.IsEmptyStruct[dto.UpdateUserDto](updateUser)
 	if isEmpty {
-		response.RenderError(w, http.StatusBadRequest, "empty request body, at least one field is required")
+		response.RenderError(w, r, user.ErrNoDataToUpdate)
 		return
 	}
 
 	err = u.userService.UpdateUserInfo(r.Context(), userID, updateUser)
 
 	if err != nil {
-		response.RenderFatalError(w, err)
+		response.RenderError(w, r, err)
 		return
 	}
 
@@ -1,4 +1,4 @@
-package http
+package http_test
 
 import (
 	"bytes"
@@ -8,42 +8,17 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	"github.com/oaxacos/vitacare/internal/config"
-	"github.com/oaxacos/vitacare/internal/domain/repository/password"
-	tokenRepository "github.com/oaxacos/vitacare/internal/domain/repository/token"
-	userRepository "github.com/oaxacos/vitacare/internal/domain/repository/user"
-	"github.com/oaxacos/vitacare/internal/domain/service/token"
-	"github.com/oaxacos/vitacare/internal/domain/service/user"
-	"github.com/oaxacos/vitacare/internal/infrastructure/db"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	bootstrapTest "github.com/oaxacos/vitacare/internal/bootstrap/bootstraptest"
+	"github.com/oaxacos/vitacare/internal/domain/model"
 	"github.com/oaxacos/vitacare/pkg/logger"
-	"github.com/oaxacos/vitacare/pkg/server"
+	pkgResponse "github.com/oaxacos/vitacare/pkg/response"
 	"github.com/oaxacos/vitacare/pkg/utils"
-	"github.com/oaxacos/vitacare/pkg/validator"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestUserController(t *testing.T) {
-
-	configTest, err := config.NewConfig("test")
-	if err != nil {
-		t.Fatalf("error loading config %v", err)
-	}
-
-	repoDb, err := db.NewConnection(configTest)
-	if err != nil {
-		t.Fatalf("error creating db connection %v", err)
-	}
-	passRepo := password.NewPasswordRepository(repoDb)
-	tokenRepo := tokenRepository.NewTokenRepository(repoDb)
-	userRepo := userRepository.NewUserRepository(repoDb)
-
-	userService := user.NewUserService(userRepo, passRepo)
-	tokenSvc := token.NewTokenService(configTest, tokenRepo)
-
-	s := server.NewServer(configTest)
-	vali := validator.New()
-
-	NewUserController(s, userService, tokenSvc, vali)
+	s := bootstrapTest.NewServer(t, nil)
 
 	t.Run("login a user", func(t *testing.T) {
 		data := map[string]interface{}{
@@ -64,10 +39,15 @@ func TestUserController(t *testing.T) {
 		if err != nil {
 			t.Fatalf("error creating request %v", err)
 		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "es-MX,es;q=0.9")
 
-		response := executeRequest(req, s)
+		response := s.Do(req)
 		assert.NotNil(t, response)
 		assert.Equal(t, http.StatusCreated, response.Code)
+		var loggedIn dto.UserLoggedInDto
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &loggedIn))
+		assert.Equal(t, "es", loggedIn.User.Language, "the language of the request is the preferred one")
 		// get cookie
 		resp := response.Result()
 		defer resp.Body.Close()
@@ -78,23 +58,90 @@ func TestUserController(t *testing.T) {
 		assert.NotEmpty(t, refreshToken)
 
 		// make again the request for the same user
-		response = executeRequest(req, s)
+		req, err = http.NewRequest("POST", "/api/v0/users/auth/register", bytes.NewBuffer(out))
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "es")
+		response = s.Do(req)
 		assert.NotNil(t, response)
-		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		var problem pkgResponse.Problem
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, "user_already_exists", problem.Code)
+		assert.Equal(t, "el usuario ya existe", problem.Detail)
+
+		// renew the session with the cookie, it needs the csrf token
+		csrfToken := resp.Header.Get(pkgResponse.CSRFHeader)
+		assert.NotEmpty(t, csrfToken)
+		renew := func(csrfHeader string) *httptest.ResponseRecorder {
+			req, err := http.NewRequest("POST", "/api/v0/users/auth/renew", nil)
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			for _, c := range cookie {
+				req.AddCookie(c)
+			}
+			if csrfHeader != "" {
+				req.Header.Set(pkgResponse.CSRFHeader, csrfHeader)
+			}
+			return s.Do(req)
+		}
+		response = renew("")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, "invalid_csrf_token", problem.Code)
+		assert.Equal(t, http.StatusForbidden, renew("forged").Code)
+		assert.Equal(t, http.StatusOK, renew(csrfToken).Code)
+	})
 
-		utils.CleanUpDB(repoDb.DB, context.TODO(), []string{"users", "user_passwords", "tokens"})
+	t.Run("v1 shares the handlers", func(t *testing.T) {
+		out, err := json.Marshal(map[string]string{"email": "test@test.com", "password": "supersecret"})
+		assert.NoError(t, err)
+		req, err := http.NewRequest("POST", "/api/v1/users/auth/login", bytes.NewBuffer(out))
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		response := s.Do(req)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, response.Header().Get("Deprecation"))
 	})
 
-}
+	t.Run("failed logins do not store the email", func(t *testing.T) {
+		out, err := json.Marshal(map[string]string{"email": "nobody@test.com", "password": "supersecret"})
+		assert.NoError(t, err)
+		req, err := http.NewRequest("POST", "/api/v1/users/auth/login", bytes.NewBuffer(out))
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		assert.Equal(t, http.StatusUnauthorized, s.Do(req).Code)
+
+		events, _, err := s.App.Repositories.Audit.List(context.Background(), model.AuditFilter{Action: string(model.AuditLoginFailed), Limit: 10})
+		assert.NoError(t, err)
+		if assert.Len(t, events, 1) {
+			assert.NotContains(t, events[0].Metadata, "email")
+			assert.NotEmpty(t, events[0].Metadata["email_hash"])
+			assert.NotContains(t, events[0].Metadata["email_hash"], "nobody")
+		}
+	})
 
-// executeRequest, creates a new ResponseRecorder
-// then executes the request by calling ServeHTTP in the router
-// after which the handler writes the response to the response recorder
-// which we can then inspect.
-func executeRequest(req *http.Request, s *server.Server) *httptest.ResponseRecorder {
-	rr := httptest.NewRecorder()
-	//s.Router.ServeHTTP(rr, req)
-	s.Mux.ServeHTTP(rr, req)
+	t.Run("the phone is stored in E.164", func(t *testing.T) {
+		admin, token := seedAdmin(t, s)
+		out, err := json.Marshal(map[string]string{"phone": "(951) 123-4567"})
+		assert.NoError(t, err)
+		req, err := http.NewRequest("PATCH", "/api/v1/users/", bytes.NewBuffer(out))
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(utils.AuthorizationKey, "Bearer "+token)
+		assert.Equal(t, http.StatusOK, s.Do(req).Code)
 
-	return rr
+		user, err := s.App.Repositories.User.GetByID(context.Background(), admin.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "+529511234567", user.Phone)
+	})
 }
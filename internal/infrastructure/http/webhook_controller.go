@@ -0,0 +1,234 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/internal/domain/service/user"
+	"github.com/oaxacos/vitacare/internal/domain/service/webhook"
+	"github.com/oaxacos/vitacare/pkg/middlewares"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/oaxacos/vitacare/pkg/validator"
+)
+
+type WebhookController struct {
+	webhookService *webhook.WebhookService
+	userService    *user.UserService
+	validator      *validator.Validator
+	config         *config.Config
+}
+
+const (
+	webhookPrefix        = "/webhooks"
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+func NewWebhookController(deps Dependencies) *WebhookController {
+	return &WebhookController{
+		webhookService: deps.Webhooks,
+		userService:    deps.Users,
+		validator:      deps.Validator,
+		config:         deps.Config,
+	}
+}
+
+func (c *WebhookController) Register(r chi.Router) {
+	r.Route(webhookPrefix, func(r chi.Router) {
+		r.Use(middlewares.AuthMiddleware(c.config, c.userService), middlewares.AdminMiddleware(c.config))
+		r.Post("/", c.handleCreateWebhook)
+		r.Get("/", c.handleListWebhooks)
+		r.Delete("/{id}", c.handleDeleteWebhook)
+		r.Get("/deliveries", c.handleListDeliveries)
+		r.Post("/deliveries/{id}/retry", c.handleRetryDelivery)
+	})
+}
+
+// @Router /webhooks/ [post]
+// @Summary subscribe to domain events
+// @Description The deliveries are signed with the returned secret in the X-Vitacare-Signature header, t=<unix time>,v1=<hex hmac sha256 of "<unix time>.<body>">
+// @Tags webhooks
+// @Security Token
+// @Param data body dto.CreateWebhookDto true "Subscription"
+// @Success 201 {object} dto.CreatedWebhookDto
+func (c *WebhookController) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var data dto.CreateWebhookDto
+	err := utils.ReadFromRequest(r, &data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	err = c.validator.ValidateStruct(r.Context(), data)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	events := make([]model.WebhookEvent, 0, len(data.Events))
+	for _, event := range data.Events {
+		events = append(events, model.WebhookEvent(event))
+	}
+
+	subscription, err := c.webhookService.CreateSubscription(r.Context(), currentUserID(r), data.URL, data.Description, events)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.CreatedWebhookDto{
+		WebhookDto: mapWebhook(subscription),
+		Secret:     subscription.Secret,
+	}
+	response.RenderJson(w, resp, http.StatusCreated)
+}
+
+// @Router /webhooks/ [get]
+// @Summary list webhook subscriptions
+// @Tags webhooks
+// @Security Token
+// @Success 200 {object} dto.WebhookListDto
+func (c *WebhookController) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := c.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.WebhookListDto{
+		Webhooks: make([]dto.WebhookDto, 0, len(subscriptions)),
+	}
+	for _, subscription := range subscriptions {
+		resp.Webhooks = append(resp.Webhooks, mapWebhook(subscription))
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+// @Router /webhooks/{id} [delete]
+// @Summary delete a webhook subscription
+// @Description The pending deliveries are dropped with the delivery log of the subscription
+// @Tags webhooks
+// @Security Token
+// @Param id path string true "Subscription ID"
+// @Success 204
+func (c *WebhookController) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	err = c.webhookService.DeleteSubscription(r.Context(), id)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Router /webhooks/deliveries [get]
+// @Summary list webhook deliveries
+// @Description The log of the deliveries, the dead ones ran out of attempts
+// @Tags webhooks
+// @Security Token
+// @Param subscription_id query string false "Subscription ID"
+// @Param event query string false "Event, e.g. patient.registered"
+// @Param status query string false "pending, delivered or dead"
+// @Param limit query int false "page size"
+// @Param offset query int false "page offset"
+// @Success 200 {object} dto.WebhookDeliveryListDto
+func (c *WebhookController) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := model.WebhookDeliveryFilter{
+		Event:  query.Get("event"),
+		Status: query.Get("status"),
+	}
+	var err error
+	filter.Limit, err = queryInt(r, "limit", defaultDeliveryLimit)
+	if err != nil || filter.Limit <= 0 || filter.Limit > maxDeliveryLimit {
+		response.RenderError(w, r, errInvalidLimit.WithArgs(maxDeliveryLimit))
+		return
+	}
+	filter.Offset, err = queryInt(r, "offset", 0)
+	if err != nil || filter.Offset < 0 {
+		response.RenderError(w, r, errInvalidOffset)
+		return
+	}
+	if value := query.Get("subscription_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			response.RenderError(w, r, errInvalidID.WithArgs("subscription_id", value))
+			return
+		}
+		filter.SubscriptionID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	deliveries, total, err := c.webhookService.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	resp := dto.WebhookDeliveryListDto{
+		Deliveries: make([]dto.WebhookDeliveryDto, 0, len(deliveries)),
+		Total:      total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	}
+	for _, delivery := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, mapWebhookDelivery(delivery))
+	}
+	response.RenderJson(w, resp, http.StatusOK)
+}
+
+// @Router /webhooks/deliveries/{id}/retry [post]
+// @Summary retry a dead delivery
+// @Description The delivery gets a new set of attempts, the first one on the next poll
+// @Tags webhooks
+// @Security Token
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} dto.WebhookDeliveryDto
+func (c *WebhookController) handleRetryDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	delivery, err := c.webhookService.RetryDelivery(r.Context(), id)
+	if err != nil {
+		response.RenderError(w, r, err)
+		return
+	}
+	response.RenderJson(w, mapWebhookDelivery(delivery), http.StatusOK)
+}
+
+func mapWebhook(subscription *model.WebhookSubscription) dto.WebhookDto {
+	resp := dto.WebhookDto{
+		ID:          subscription.ID,
+		URL:         subscription.URL,
+		Description: subscription.Description,
+		Events:      make([]string, 0, len(subscription.Events)),
+		CreatedBy:   subscription.CreatedBy,
+		CreatedAt:   subscription.CreatedAt,
+	}
+	for _, event := range subscription.Events {
+		resp.Events = append(resp.Events, string(event))
+	}
+	return resp
+}
+
+func mapWebhookDelivery(delivery *model.WebhookDelivery) dto.WebhookDeliveryDto {
+	return dto.WebhookDeliveryDto{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		Event:          string(delivery.Event),
+		Payload:        delivery.Payload,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  nullTimePtr(delivery.NextAttemptAt, delivery.Status == model.WebhookPending),
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    nullTimePtr(delivery.DeliveredAt.Time, delivery.DeliveredAt.Valid),
+	}
+}
@@ -0,0 +1,222 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/application/dto"
+	bootstrapTest "github.com/oaxacos/vitacare/internal/bootstrap/bootstraptest"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func TestWebhookController(t *testing.T) {
+	s := bootstrapTest.NewServer(t, nil)
+	_, adminToken := seedAdmin(t, s)
+
+	var (
+		mu       sync.Mutex
+		received []receivedWebhook
+		status   = http.StatusNoContent
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, receivedWebhook{header: r.Header.Clone(), body: body})
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+	// the receiver runs on its own goroutines, the test reads its state
+	// under the lock
+	receivedWebhooks := func() []receivedWebhook {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(received)
+	}
+	respondWith := func(code int) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = code
+	}
+
+	send := func(method, path string, body any) *http.Response {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req, err := http.NewRequest(method, path, &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(utils.AuthorizationKey, "Bearer "+adminToken)
+		return s.Do(req).Result()
+	}
+	deliver := func() {
+		_, err := s.App.Services.Webhook.DeliverDue(context.Background())
+		require.NoError(t, err)
+	}
+
+	resp := send(http.MethodPost, "/api/v1/webhooks/", map[string]any{"url": receiver.URL, "events": []string{"user.deleted"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = send(http.MethodPost, "/api/v1/webhooks/", map[string]any{"url": receiver.URL, "events": []string{"patient.registered"}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created dto.CreatedWebhookDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.NotEmpty(t, created.Secret)
+
+	register := func(email string) uuid.UUID {
+		resp := send(http.MethodPost, "/api/v1/users/auth/register", map[string]any{
+			"first_name":            "Maria",
+			"last_name":             "Lopez",
+			"email":                 email,
+			"password":              "supersecret",
+			"password_confirmation": "supersecret",
+		})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var loggedIn dto.UserLoggedInDto
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&loggedIn))
+		return loggedIn.User.ID
+	}
+
+	t.Run("a patient registration is delivered signed", func(t *testing.T) {
+		id := register("maria@test.com")
+		deliver()
+		webhooks := receivedWebhooks()
+		require.Len(t, webhooks, 1)
+		webhook := webhooks[0]
+		assert.Equal(t, "patient.registered", webhook.header.Get("X-Vitacare-Event"))
+		subscription := &model.WebhookSubscription{Secret: created.Secret}
+		signature := webhook.header.Get(model.WebhookSignatureHeader)
+		var timestamp int64
+		_, err := fmt.Sscanf(signature, "t=%d,", &timestamp)
+		require.NoError(t, err)
+		assert.Equal(t, subscription.Sign(time.Unix(timestamp, 0), webhook.body), signature)
+
+		var payload struct {
+			Event string                      `json:"event"`
+			Data  model.PatientRegisteredData `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(webhook.body, &payload))
+		assert.Equal(t, "patient.registered", payload.Event)
+		assert.Equal(t, id, payload.Data.ID)
+		assert.NotContains(t, string(webhook.body), "maria@test.com", "the delivery log keeps no personal data")
+	})
+
+	t.Run("a failed delivery is retried later", func(t *testing.T) {
+		respondWith(http.StatusInternalServerError)
+		register("juan@test.com")
+		deliver()
+		deliver()
+		assert.Len(t, receivedWebhooks(), 2, "the next attempt waits for the backoff")
+
+		resp := send(http.MethodGet, "/api/v1/webhooks/deliveries?status=pending", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var deliveries dto.WebhookDeliveryListDto
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&deliveries))
+		require.Equal(t, 1, deliveries.Total)
+		assert.Equal(t, 1, deliveries.Deliveries[0].Attempts)
+		assert.Equal(t, http.StatusInternalServerError, deliveries.Deliveries[0].ResponseStatus)
+		assert.NotNil(t, deliveries.Deliveries[0].NextAttemptAt)
+
+		resp = send(http.MethodPost, "/api/v1/webhooks/deliveries/"+deliveries.Deliveries[0].ID.String()+"/retry", nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, "only dead deliveries are retried")
+	})
+}
+
+func TestWebhookPrivateNetworks(t *testing.T) {
+	conf, err := config.NewConfig("test")
+	require.NoError(t, err)
+	conf.Webhooks.AllowPrivateNetworks = false
+	s := bootstrapTest.NewServer(t, conf)
+	_, adminToken := seedAdmin(t, s)
+
+	hits := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits <- struct{}{}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	// localhost resolves to a loopback address like a rebound dns name would
+	localURL := strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+
+	for _, rawURL := range []string{receiver.URL, localURL, "http://169.254.169.254/latest/meta-data", "http://10.0.0.1/hook"} {
+		out, err := json.Marshal(map[string]any{"url": rawURL, "events": []string{"patient.registered"}})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/webhooks/", bytes.NewBuffer(out))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(utils.AuthorizationKey, "Bearer "+adminToken)
+		response := s.Do(req)
+		assert.Equal(t, http.StatusBadRequest, response.Code, rawURL)
+		assert.Contains(t, response.Body.String(), "webhook_url_not_public", rawURL)
+	}
+
+	t.Run("the dialer refuses the addresses that are not public", func(t *testing.T) {
+		ctx := context.Background()
+		subscription, err := model.NewWebhookSubscription(localURL, "", "secret", []model.WebhookEvent{model.WebhookPatientRegistered}, uuid.New())
+		require.NoError(t, err)
+		require.NoError(t, s.App.Repositories.Webhook.Save(ctx, subscription))
+		require.NoError(t, s.App.Services.Webhook.Publish(ctx, model.WebhookPatientRegistered, model.PatientRegisteredData{ID: uuid.New()}))
+
+		n, err := s.App.Services.Webhook.DeliverDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		select {
+		case <-hits:
+			t.Fatal("the delivery reached a loopback address")
+		default:
+		}
+		deliveries, _, err := s.App.Services.Webhook.ListDeliveries(ctx, model.WebhookDeliveryFilter{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Contains(t, deliveries[0].LastError, "not public")
+	})
+}
+
+func TestWebhooksDisabled(t *testing.T) {
+	conf, err := config.NewConfig("test")
+	require.NoError(t, err)
+	conf.Webhooks.Enabled = false
+	s := bootstrapTest.NewServer(t, conf)
+	_, adminToken := seedAdmin(t, s)
+	assert.Nil(t, s.App.Services.Webhook, "the module is not built")
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/webhooks/", nil)
+	require.NoError(t, err)
+	req.Header.Set(utils.AuthorizationKey, "Bearer "+adminToken)
+	assert.Equal(t, http.StatusNotFound, s.Do(req).Code)
+
+	out, err := json.Marshal(map[string]any{
+		"first_name":            "Ana",
+		"last_name":             "Ruiz",
+		"email":                 "ana@test.com",
+		"password":              "supersecret",
+		"password_confirmation": "supersecret",
+	})
+	require.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, "/api/v1/users/auth/register", bytes.NewBuffer(out))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	assert.Equal(t, http.StatusCreated, s.Do(req).Code, "the events are dropped")
+}
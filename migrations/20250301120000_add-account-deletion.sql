@@ -0,0 +1,17 @@
+-- migrate:up
+ALTER TABLE "users" ADD COLUMN "deletion_requested_at" timestamptz DEFAULT null;
+ALTER TABLE "users" ADD COLUMN "anonymized_at" timestamptz DEFAULT null;
+
+CREATE INDEX "users_deletion_requested_at_index" ON "users" ("deletion_requested_at")
+  WHERE "deletion_requested_at" IS NOT NULL AND "anonymized_at" IS NULL;
+CREATE INDEX "address_user_id_index" ON "address" ("user_id");
+CREATE INDEX "medical_ensure_user_id_index" ON "medical_ensure" ("user_id");
+CREATE INDEX "medical_appointments_patient_id_index" ON "medical_appointments" ("patient_id");
+
+-- migrate:down
+DROP INDEX IF EXISTS "medical_appointments_patient_id_index";
+DROP INDEX IF EXISTS "medical_ensure_user_id_index";
+DROP INDEX IF EXISTS "address_user_id_index";
+DROP INDEX IF EXISTS "users_deletion_requested_at_index";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "anonymized_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deletion_requested_at";
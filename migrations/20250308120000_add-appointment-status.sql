@@ -0,0 +1,23 @@
+-- migrate:up
+DO $$ BEGIN
+CREATE TYPE "appointment_status" AS ENUM (
+  'scheduled',
+  'completed',
+  'cancelled'
+);
+EXCEPTION
+    WHEN duplicate_object THEN null;
+END $$;
+
+ALTER TABLE "medical_appointments" ADD COLUMN "status" appointment_status DEFAULT 'scheduled';
+ALTER TABLE "medical_appointments" ADD COLUMN "cancelled_at" timestamptz DEFAULT null;
+ALTER TABLE "medical_appointments" ADD COLUMN "cancellation_reason" text DEFAULT null;
+
+CREATE INDEX "users_deceased_at_index" ON "users" ("deceased_at") WHERE "deceased_at" IS NOT NULL;
+
+-- migrate:down
+DROP INDEX IF EXISTS "users_deceased_at_index";
+ALTER TABLE "medical_appointments" DROP COLUMN IF EXISTS "cancellation_reason";
+ALTER TABLE "medical_appointments" DROP COLUMN IF EXISTS "cancelled_at";
+ALTER TABLE "medical_appointments" DROP COLUMN IF EXISTS "status";
+DROP TYPE IF EXISTS "appointment_status";
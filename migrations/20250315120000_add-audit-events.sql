@@ -0,0 +1,34 @@
+-- migrate:up
+CREATE TABLE "audit_events" (
+  "id" uuid PRIMARY KEY,
+  "seq" bigserial UNIQUE NOT NULL,
+  "action" text NOT NULL,
+  "actor_id" uuid DEFAULT null,
+  "target_id" uuid DEFAULT null,
+  "ip" text,
+  "request_id" text,
+  "metadata" jsonb,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "prev_hash" text NOT NULL,
+  "hash" text NOT NULL
+);
+
+CREATE INDEX "audit_events_actor_id_index" ON "audit_events" ("actor_id");
+CREATE INDEX "audit_events_target_id_index" ON "audit_events" ("target_id");
+CREATE INDEX "audit_events_action_created_at_index" ON "audit_events" ("action", "created_at");
+
+-- audit events are append only
+CREATE OR REPLACE FUNCTION audit_events_immutable() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_events_no_update"
+  BEFORE UPDATE OR DELETE ON "audit_events"
+  FOR EACH ROW EXECUTE FUNCTION audit_events_immutable();
+
+-- migrate:down
+DROP TRIGGER IF EXISTS "audit_events_no_update" ON "audit_events";
+DROP FUNCTION IF EXISTS audit_events_immutable();
+DROP TABLE IF EXISTS "audit_events";
@@ -0,0 +1,6 @@
+-- migrate:up
+-- the default of i18n.DefaultLocale, the users pick theirs in their profile
+ALTER TABLE "users" ADD COLUMN "language" varchar(5) NOT NULL DEFAULT 'en';
+
+-- migrate:down
+ALTER TABLE "users" DROP COLUMN IF EXISTS "language";
@@ -0,0 +1,12 @@
+-- migrate:up
+-- state of the postgres rate limit store, tat is the theoretical arrival
+-- time of the next request of the key
+CREATE TABLE "rate_limits" (
+  "key" text PRIMARY KEY,
+  "tat" timestamptz NOT NULL
+);
+
+CREATE INDEX "rate_limits_tat_index" ON "rate_limits" ("tat");
+
+-- migrate:down
+DROP TABLE IF EXISTS "rate_limits";
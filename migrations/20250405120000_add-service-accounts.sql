@@ -0,0 +1,29 @@
+-- migrate:up
+CREATE TABLE "service_accounts" (
+  "id" uuid PRIMARY KEY,
+  "name" text UNIQUE NOT NULL,
+  "description" text NOT NULL DEFAULT '',
+  "created_by" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE "api_keys" (
+  "id" uuid PRIMARY KEY,
+  "service_account_id" uuid NOT NULL REFERENCES "service_accounts" ("id"),
+  "name" text NOT NULL,
+  -- the public part of the key, only the hash of the secret is stored
+  "prefix" text UNIQUE NOT NULL,
+  "hash" text NOT NULL,
+  "scopes" text[] NOT NULL DEFAULT '{}',
+  "rate_limit" integer NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "expires_at" timestamptz,
+  "revoked_at" timestamptz,
+  "last_used_at" timestamptz
+);
+
+CREATE INDEX "api_keys_service_account_id_index" ON "api_keys" ("service_account_id");
+
+-- migrate:down
+DROP TABLE IF EXISTS "api_keys";
+DROP TABLE IF EXISTS "service_accounts";
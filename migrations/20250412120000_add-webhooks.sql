@@ -0,0 +1,34 @@
+-- migrate:up
+CREATE TABLE "webhook_subscriptions" (
+  "id" uuid PRIMARY KEY,
+  "url" text NOT NULL,
+  "description" text NOT NULL DEFAULT '',
+  "events" text[] NOT NULL DEFAULT '{}',
+  -- signs the deliveries, it can't be hashed like the api keys
+  "secret" text NOT NULL,
+  "created_by" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE "webhook_deliveries" (
+  "id" uuid PRIMARY KEY,
+  "subscription_id" uuid NOT NULL REFERENCES "webhook_subscriptions" ("id") ON DELETE CASCADE,
+  "event_id" uuid NOT NULL,
+  "event" text NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" text NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "response_status" integer NOT NULL DEFAULT 0,
+  "last_error" text NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "delivered_at" timestamptz
+);
+
+-- the dispatcher polls the pending deliveries that are due
+CREATE INDEX "webhook_deliveries_due_index" ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+CREATE INDEX "webhook_deliveries_subscription_id_index" ON "webhook_deliveries" ("subscription_id", "created_at");
+
+-- migrate:down
+DROP TABLE IF EXISTS "webhook_deliveries";
+DROP TABLE IF EXISTS "webhook_subscriptions";
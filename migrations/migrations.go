@@ -0,0 +1,122 @@
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS holds the dbmate migrations so the binary knows the schema it expects.
+//
+//go:embed *.sql
+var FS embed.FS
+
+const (
+	upMarker      = "-- migrate:up"
+	downMarker    = "-- migrate:down"
+	noTransaction = "transaction:false"
+	versionLayout = "20060102150405"
+)
+
+var (
+	ErrInvalidMigration  = errors.New("invalid migration")
+	ErrInvalidName       = errors.New("migration name must only contain letters, numbers, - and _")
+	validMigrationName   = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+)
+
+// Migration is a dbmate migration file, the statements of each direction run
+// in a transaction unless the marker says transaction:false.
+type Migration struct {
+	Version         string
+	Name            string
+	Up              string
+	Down            string
+	UpTransaction   bool
+	DownTransaction bool
+}
+
+// Load parses every embedded migration ordered by version.
+func Load() ([]Migration, error) {
+	files, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		content, err := fs.ReadFile(FS, file)
+		if err != nil {
+			return nil, err
+		}
+		migration, err := Parse(file, string(content))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Parse reads the up and down sections of a migration file.
+func Parse(file, content string) (Migration, error) {
+	match := migrationFilePattern.FindStringSubmatch(file)
+	if match == nil {
+		return Migration{}, fmt.Errorf("%w: %s is not named <version>_<name>.sql", ErrInvalidMigration, file)
+	}
+	migration := Migration{
+		Version:         match[1],
+		Name:            match[2],
+		UpTransaction:   true,
+		DownTransaction: true,
+	}
+
+	upStart := strings.Index(content, upMarker)
+	if upStart < 0 {
+		return Migration{}, fmt.Errorf("%w: %s has no %q section", ErrInvalidMigration, file, upMarker)
+	}
+	downStart := strings.Index(content, downMarker)
+
+	up := content[upStart:]
+	if downStart > upStart {
+		up = content[upStart:downStart]
+	}
+	migration.Up, migration.UpTransaction = section(up)
+	if downStart >= 0 {
+		down := content[downStart:]
+		if downStart < upStart {
+			down = content[downStart:upStart]
+		}
+		migration.Down, migration.DownTransaction = section(down)
+	}
+	return migration, nil
+}
+
+// section splits the marker line from the statements.
+func section(content string) (string, bool) {
+	marker, statements, _ := strings.Cut(content, "\n")
+	return strings.TrimSpace(statements), !strings.Contains(marker, noTransaction)
+}
+
+// Create writes an empty migration into dir, the binary has to be rebuilt to
+// embed it.
+func Create(dir, name string) (string, error) {
+	if !validMigrationName.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	file := filepath.Join(dir, fmt.Sprintf("%s_%s.sql", time.Now().UTC().Format(versionLayout), name))
+	content := upMarker + "\n\n\n" + downMarker + "\n\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("reads both sections", func(t *testing.T) {
+		content := "-- migrate:up\nCREATE TABLE a (id int);\n\n-- migrate:down\nDROP TABLE a;\n"
+		migration, err := Parse("20250101000000_create-a.sql", content)
+		assert.NoError(t, err)
+		assert.Equal(t, "20250101000000", migration.Version)
+		assert.Equal(t, "create-a", migration.Name)
+		assert.Equal(t, "CREATE TABLE a (id int);", migration.Up)
+		assert.Equal(t, "DROP TABLE a;", migration.Down)
+		assert.True(t, migration.UpTransaction)
+	})
+
+	t.Run("runs outside a transaction", func(t *testing.T) {
+		content := "-- migrate:up transaction:false\nCREATE INDEX CONCURRENTLY b ON a (id);\n"
+		migration, err := Parse("20250101000001_index.sql", content)
+		assert.NoError(t, err)
+		assert.False(t, migration.UpTransaction)
+		assert.Empty(t, migration.Down)
+	})
+
+	t.Run("rejects invalid files", func(t *testing.T) {
+		_, err := Parse("create-a.sql", "-- migrate:up\n")
+		assert.ErrorIs(t, err, ErrInvalidMigration)
+		_, err = Parse("20250101000000_create-a.sql", "CREATE TABLE a (id int);")
+		assert.ErrorIs(t, err, ErrInvalidMigration)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	for i := 1; i < len(migrations); i++ {
+		assert.Less(t, migrations[i-1].Version, migrations[i].Version)
+	}
+	for _, migration := range migrations {
+		assert.NotEmpty(t, migration.Up, migration.Name)
+	}
+}
@@ -0,0 +1,154 @@
+// Package apperror defines the errors the domain hands to the transport
+// layer. Every error has a kind, which decides the http status, and a stable
+// code clients can match on. The message is safe to show to the client, the
+// wrapped cause is only logged.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindInternal     Kind = "internal"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	// the request body is too large or not in a format we accept
+	KindPayloadTooLarge      Kind = "payload_too_large"
+	KindUnsupportedMediaType Kind = "unsupported_media_type"
+	KindTooManyRequests      Kind = "too_many_requests"
+)
+
+var (
+	ErrInternal       = Internal("internal_error", "something went wrong")
+	ErrNotFound       = NotFound("not_found", "resource not found")
+	ErrUnauthorized   = Unauthorized("unauthorized", "unauthorized")
+	ErrForbidden      = Forbidden("forbidden", "forbidden")
+	ErrInvalidRequest = Validation("invalid_request", "invalid request")
+)
+
+type Error struct {
+	Kind Kind
+	Code string
+	// Message is the english text, it can have verbs filled by Args.
+	Message string
+	Args    []any
+	// Fields lists the invalid fields of a validation error.
+	Fields []FieldError
+	// Err is the cause, it is logged but never sent to the client.
+	Err error
+}
+
+// FieldError describes why a field of the request is invalid, Message is
+// already translated to the language of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func Internal(code, message string) *Error {
+	return New(KindInternal, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if len(e.Args) > 0 {
+		message = fmt.Sprintf(message, e.Args...)
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]string, 0, len(e.Fields))
+		for _, field := range e.Fields {
+			fields = append(fields, field.Message)
+		}
+		message += ": " + strings.Join(fields, "; ")
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any error with the same kind and code, so a wrapped copy of a
+// sentinel is still the sentinel for errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// WithArgs returns a copy of e with the values of the verbs in its message.
+func (e *Error) WithArgs(args ...any) *Error {
+	withArgs := *e
+	withArgs.Args = args
+	return &withArgs
+}
+
+// WithFields returns a copy of e with the invalid fields of the request.
+func (e *Error) WithFields(fields []FieldError) *Error {
+	withFields := *e
+	withFields.Fields = fields
+	return &withFields
+}
+
+// As returns the first *Error in the chain of err.
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of err, errors outside the taxonomy are internal.
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return KindInternal
+}
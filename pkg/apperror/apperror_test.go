@@ -0,0 +1,28 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	errUserNotFound := NotFound("user_not_found", "user not found")
+	cause := errors.New("connection refused")
+
+	wrapped := fmt.Errorf("getting user: %w", errUserNotFound.Wrap(cause))
+	assert.ErrorIs(t, wrapped, errUserNotFound)
+	assert.ErrorIs(t, wrapped, cause)
+	assert.NotErrorIs(t, wrapped, ErrNotFound, "the code is part of the identity")
+	assert.Equal(t, KindNotFound, KindOf(wrapped))
+	assert.Nil(t, errUserNotFound.Err, "wrap does not modify the sentinel")
+
+	appErr, ok := As(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, "user_not_found", appErr.Code)
+	assert.Equal(t, "user not found: connection refused", appErr.Error())
+
+	assert.Equal(t, KindInternal, KindOf(cause))
+}
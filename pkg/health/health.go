@@ -0,0 +1,185 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	defaultTimeout  = 2 * time.Second
+	defaultCacheTTL = 5 * time.Second
+)
+
+type CheckFunc func(ctx context.Context) error
+
+// Check is a dependency the service needs, a failing check that is not
+// critical is reported but does not make the service unready.
+type Check struct {
+	Name     string
+	Check    CheckFunc
+	Timeout  time.Duration
+	Critical bool
+}
+
+type CheckResult struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Critical   bool      `json:"critical"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+func (r Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+// Registry runs the registered checks and caches their results so the probes
+// don't hit the dependencies on every request. The concurrent probes share
+// the run of a check.
+type Registry struct {
+	group    singleflight.Group
+	mu       sync.Mutex
+	checks   []Check
+	cache    map[string]CheckResult
+	cacheTTL time.Duration
+	timeout  time.Duration
+}
+
+func NewRegistry(timeout, cacheTTL time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	return &Registry{
+		cache:    map[string]CheckResult{},
+		cacheTTL: cacheTTL,
+		timeout:  timeout,
+	}
+}
+
+func (r *Registry) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = r.timeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+}
+
+// Run executes the checks in parallel, reusing the results younger than the
+// cache ttl.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	checks := append([]Check(nil), r.checks...)
+	r.mu.Unlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		if cached, ok := r.cached(check.Name); ok {
+			results[i] = cached
+			continue
+		}
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = r.shared(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, result := range results {
+		if result.Critical && result.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+	return report
+}
+
+func (r *Registry) cached(name string) (CheckResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, ok := r.cache[name]
+	if !ok || time.Since(result.CheckedAt) > r.cacheTTL {
+		return CheckResult{}, false
+	}
+	return result, true
+}
+
+// shared joins the run of check in progress or starts one. The run outlives
+// the caller, a prober that disconnects gets a result that is not cached so
+// it can't mark a healthy dependency as down.
+func (r *Registry) shared(ctx context.Context, check Check) CheckResult {
+	runCtx := context.WithoutCancel(ctx)
+	ch := r.group.DoChan(check.Name, func() (any, error) {
+		if cached, ok := r.cached(check.Name); ok {
+			return cached, nil
+		}
+		return r.run(runCtx, check), nil
+	})
+	select {
+	case res := <-ch:
+		return res.Val.(CheckResult)
+	case <-ctx.Done():
+		return CheckResult{
+			Name:      check.Name,
+			Status:    StatusDown,
+			Critical:  check.Critical,
+			Error:     ctx.Err().Error(),
+			CheckedAt: time.Now(),
+		}
+	}
+}
+
+func (r *Registry) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- check.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:       check.Name,
+		Status:     StatusUp,
+		Critical:   check.Critical,
+		DurationMs: time.Since(start).Milliseconds(),
+		CheckedAt:  time.Now(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.cache[check.Name] = result
+	r.mu.Unlock()
+	return result
+}
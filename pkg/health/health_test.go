@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("optional checks don't make it unhealthy", func(t *testing.T) {
+		registry := NewRegistry(time.Second, time.Minute)
+		registry.Register(Check{Name: "database", Critical: true, Check: func(ctx context.Context) error { return nil }})
+		registry.Register(Check{Name: "mailer", Check: func(ctx context.Context) error { return errors.New("smtp down") }})
+
+		report := registry.Run(context.Background())
+		assert.True(t, report.Healthy())
+		assert.Equal(t, "database", report.Checks[0].Name)
+		assert.Equal(t, StatusDown, report.Checks[1].Status)
+		assert.Equal(t, "smtp down", report.Checks[1].Error)
+	})
+
+	t.Run("a slow check times out", func(t *testing.T) {
+		registry := NewRegistry(time.Second, time.Minute)
+		registry.Register(Check{Name: "database", Critical: true, Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}})
+
+		start := time.Now()
+		report := registry.Run(context.Background())
+		assert.False(t, report.Healthy())
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+	})
+
+	t.Run("results are cached", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := NewRegistry(time.Second, time.Minute)
+		registry.Register(Check{Name: "database", Check: func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		}})
+
+		registry.Run(context.Background())
+		registry.Run(context.Background())
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("a cancelled probe is not cached", func(t *testing.T) {
+		registry := NewRegistry(time.Second, time.Minute)
+		registry.Register(Check{Name: "database", Critical: true, Check: func(ctx context.Context) error {
+			select {
+			case <-time.After(50 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		report := registry.Run(ctx)
+		assert.False(t, report.Healthy())
+		assert.Equal(t, context.Canceled.Error(), report.Checks[0].Error)
+
+		report = registry.Run(context.Background())
+		assert.True(t, report.Healthy(), "the check kept running for the next probe")
+	})
+
+	t.Run("concurrent probes share a run", func(t *testing.T) {
+		var calls atomic.Int32
+		registry := NewRegistry(time.Second, time.Minute)
+		registry.Register(Check{Name: "database", Check: func(ctx context.Context) error {
+			calls.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}})
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.True(t, registry.Run(context.Background()).Healthy())
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// catalog maps every locale to its messages, keyed by the code of the error
+// or message.
+var catalog = loadCatalog()
+
+func loadCatalog() map[string]map[string]string {
+	files, err := localesFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	catalog := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		content, err := localesFS.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(content, &messages); err != nil {
+			panic(fmt.Sprintf("invalid catalog %s: %s", file.Name(), err))
+		}
+		catalog[strings.TrimSuffix(file.Name(), ".json")] = messages
+	}
+	return catalog
+}
+
+// Message returns the message of code in locale, falling back to the default
+// locale when it is not translated.
+func Message(locale, code string) (string, bool) {
+	if message, ok := catalog[locale][code]; ok {
+		return message, true
+	}
+	message, ok := catalog[DefaultLocale][code]
+	return message, ok
+}
+
+// T translates code to the language of ctx, args fill the verbs of the
+// message. An unknown code is returned as is.
+func T(ctx context.Context, code string, args ...any) string {
+	message, ok := Message(FromContext(ctx), code)
+	if !ok {
+		return code
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Supported reports if there is a catalog for locale.
+func Supported(locale string) bool {
+	_, ok := catalog[locale]
+	return ok
+}
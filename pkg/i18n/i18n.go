@@ -0,0 +1,48 @@
+// Package i18n picks the language of the responses from the Accept-Language
+// header of the request.
+package i18n
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	English = "en"
+	Spanish = "es"
+	// DefaultLocale is used when the client doesn't ask for a supported
+	// language.
+	DefaultLocale = English
+)
+
+const localeContextKey = "locale"
+
+// the first tag is the fallback of the matcher
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Spanish})
+
+// Parse returns the supported locale that best matches an Accept-Language
+// header, es-MX and es-419 are Spanish.
+func Parse(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	tag, _, _ := matcher.Match(tags...)
+	base, _ := tag.Base()
+	return base.String()
+}
+
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey, locale)
+}
+
+// FromContext returns the locale of the request, or the default one outside
+// of a request.
+func FromContext(ctx context.Context) string {
+	locale, ok := ctx.Value(localeContextKey).(string)
+	if !ok || locale == "" {
+		return DefaultLocale
+	}
+	return locale
+}
@@ -0,0 +1,45 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"":                        English,
+		"es":                      Spanish,
+		"es-MX,es;q=0.9,en;q=0.8": Spanish,
+		"es-419":                  Spanish,
+		"en-US,en;q=0.9":          English,
+		"fr-FR,es;q=0.5":          Spanish,
+		"de":                      English,
+		"not a header;;":          English,
+	}
+	for header, expected := range tests {
+		assert.Equal(t, expected, Parse(header), "Accept-Language: %q", header)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, DefaultLocale, FromContext(context.Background()))
+	assert.Equal(t, Spanish, FromContext(WithLocale(context.Background(), Spanish)))
+}
+
+func TestCatalog(t *testing.T) {
+	for locale, messages := range catalog {
+		for code := range catalog[DefaultLocale] {
+			assert.Contains(t, messages, code, "%s is not translated to %s", code, locale)
+		}
+	}
+
+	ctx := WithLocale(context.Background(), Spanish)
+	assert.Equal(t, "usuario no encontrado", T(ctx, "user_not_found"))
+	assert.Equal(t, "el límite debe estar entre 1 y 100", T(ctx, "invalid_limit", 100))
+	assert.Equal(t, "user not found", T(context.Background(), "user_not_found"))
+	assert.Equal(t, "unknown_code", T(ctx, "unknown_code"))
+	assert.True(t, Supported(Spanish))
+	assert.False(t, Supported("fr"))
+}
@@ -13,17 +13,62 @@ var (
 	once             = sync.Once{}
 	log              *zap.SugaredLogger
 	loggerContextKey = "ctx_logger"
+	// level of the logger built by Init, SetLevel changes it at runtime
+	level = zap.NewAtomicLevel()
 )
 
 func setGlobalLogger(logger *zap.SugaredLogger) {
 	log = logger
 }
 
+const JSONFormat = "json"
+
 func New(config *config.Config) *zap.SugaredLogger {
-	return NewLogger(config.Server.Debug, config.Server.Pretty)
+	return NewLogger(config.Server.Debug, config.Server.Pretty, config.Server.LogFormat == JSONFormat)
+}
+
+// Init builds the logger from the config and uses it as the global logger.
+func Init(config *config.Config) *zap.SugaredLogger {
+	_ = SetLevel(Level(config))
+	logger := newLogger(level, config.Server.Pretty, config.Server.LogFormat == JSONFormat)
+	once.Do(func() {})
+	setGlobalLogger(logger)
+	return logger
+}
+
+// Level returns the configured level, debug mode logs everything by default.
+func Level(config *config.Config) string {
+	if config.Server.LogLevel != "" {
+		return config.Server.LogLevel
+	}
+	if config.Server.Debug {
+		return zapcore.DebugLevel.String()
+	}
+	return zapcore.InfoLevel.String()
+}
+
+// SetLevel changes the level of the global logger without rebuilding it.
+func SetLevel(name string) error {
+	parsed, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+// NewLogger builds a logger writing to stdout, jsonOutput is meant for
+// production where the logs are collected, otherwise it is human-readable.
+// Fields like email, dni or phone are always redacted.
+func NewLogger(debugMode, prettyLog, jsonOutput bool) *zap.SugaredLogger {
+	logLevel := zapcore.InfoLevel
+	if debugMode {
+		logLevel = zapcore.DebugLevel
+	}
+	return newLogger(logLevel, prettyLog, jsonOutput)
 }
 
-func NewLogger(debugMode, prettyLog bool) *zap.SugaredLogger {
+func newLogger(logLevel zapcore.LevelEnabler, prettyLog, jsonOutput bool) *zap.SugaredLogger {
 	//Define the encoder with color support
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "time",
@@ -37,24 +82,23 @@ func NewLogger(debugMode, prettyLog bool) *zap.SugaredLogger {
 		EncodeDuration: zapcore.StringDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder, // Short file path
 	}
-	if prettyLog {
+	if prettyLog && !jsonOutput {
 		// Adds color to logs
 		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	}
 
 	// Create a core with the level and colored output
-	consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-
-	logLevel := zapcore.InfoLevel
-	if debugMode {
-		logLevel = zapcore.DebugLevel
+	encoder := zapcore.NewConsoleEncoder(encoderConfig)
+	if jsonOutput {
+		encoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
 	}
 
 	// Output to stdout
 	consoleOutput := zapcore.Lock(os.Stdout)
 
 	// Combine the encoder, level, and output into a core
-	core := zapcore.NewCore(consoleEncoder, consoleOutput, logLevel)
+	core := newRedactCore(zapcore.NewCore(encoder, consoleOutput, logLevel))
 
 	// Build the logger
 	return zap.New(core, zap.AddCaller()).Sugar()
@@ -66,11 +110,11 @@ func GetGlobalLogger(params ...bool) *zap.SugaredLogger {
 		var log *zap.SugaredLogger
 		if len(params) == 0 {
 			// default logger is in debug mode and pretty logs
-			log = NewLogger(true, true)
+			log = NewLogger(true, true, false)
 		} else if len(params) == 1 {
-			log = NewLogger(params[0], params[0])
+			log = NewLogger(params[0], params[0], false)
 		} else {
-			log = NewLogger(params[0], params[1])
+			log = NewLogger(params[0], params[1], false)
 		}
 
 		setGlobalLogger(log)
@@ -99,3 +143,37 @@ func GetContextLogger(ctx context.Context) *zap.SugaredLogger {
 func SetContextLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
 	return context.WithValue(ctx, loggerContextKey, logger)
 }
+
+var requestFieldsKey = "ctx_request_fields"
+
+// requestFields are filled while the request goes through the middlewares,
+// like the user id once authenticated, and written in the access log.
+type requestFields struct {
+	mu     sync.Mutex
+	fields []any
+}
+
+func WithRequestFields(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestFieldsKey, &requestFields{})
+}
+
+// AddRequestFields adds key value pairs to the access log of the request.
+func AddRequestFields(ctx context.Context, keysAndValues ...any) {
+	holder, ok := ctx.Value(requestFieldsKey).(*requestFields)
+	if !ok {
+		return
+	}
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	holder.fields = append(holder.fields, keysAndValues...)
+}
+
+func GetRequestFields(ctx context.Context) []any {
+	holder, ok := ctx.Value(requestFieldsKey).(*requestFields)
+	if !ok {
+		return nil
+	}
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	return append([]any(nil), holder.fields...)
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// sensitiveKeys are the field names whose value never reaches the logs
+	sensitiveKeys = map[string]bool{
+		"email":         true,
+		"dni":           true,
+		"phone":         true,
+		"password":      true,
+		"token":         true,
+		"access_token":  true,
+		"refresh_token": true,
+		"authorization": true,
+	}
+	// valuePatterns match the personal data written inside messages, the
+	// emails, the CURP and voter keys used as dni, and the phones in E.164 or
+	// written with separators, a bare run of digits is more likely a
+	// timestamp or a count
+	valuePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+		regexp.MustCompile(`(?i)\b[A-Z][AEIOUX][A-Z]{2}\d{6}[HMX][A-Z]{5}[A-Z\d]\d\b`),
+		regexp.MustCompile(`(?i)\b[A-Z]{6}\d{8}[HMX]\d{3}\b`),
+		regexp.MustCompile(`\+[1-9](?:[ .\-]?\d){7,14}\b|\([1-9]\d{2}\) ?\d{3}[ .\-]?\d{4}\b|\b[1-9]\d{2}[ .\-]\d{3}[ .\-]\d{4}\b`),
+	}
+)
+
+// redactCore removes personal data from the fields and messages before they
+// are written.
+type redactCore struct {
+	zapcore.Core
+}
+
+func newRedactCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = RedactString(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// RedactString masks the emails, dni and phones found in s.
+func RedactString(s string) string {
+	for _, pattern := range valuePatterns {
+		s = pattern.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+func isSensitiveKey(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, field := range fields {
+		replacement, changed := redactField(field)
+		if !changed {
+			if out != nil {
+				out = append(out, field)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, replacement)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func redactField(field zapcore.Field) (zapcore.Field, bool) {
+	if isSensitiveKey(field.Key) {
+		return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: redacted}, true
+	}
+	if field.Type == zapcore.StringType {
+		if value := RedactString(field.String); value != field.String {
+			field.String = value
+			return field, true
+		}
+	}
+	return field, false
+}
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(newRedactCore(core)).Sugar()
+
+	log.With("email", "jose@test.com").Infow("user registered",
+		"dni", "RUJJ900101HOCZSS09",
+		"phone", "9511234567",
+		"user_id", "42",
+	)
+	log.Infof("user %s logged in", "jose@test.com")
+	log.Infow("dni RUJJ900101HOCZSS09 and voter key GMVLMR80070501M100 updated", "note", "call +52 951 123 4567")
+	log.Infof("phone (951) 123-4567 updated after %d attempts, user %d", 3, 42)
+	log.Infof("phones 951-123-4567 and 951.123.4567 synced at %d, %d bytes", 1712345678, 1048576000)
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 5)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, redacted, fields["email"])
+	assert.Equal(t, redacted, fields["dni"])
+	assert.Equal(t, redacted, fields["phone"])
+	assert.Equal(t, "42", fields["user_id"])
+
+	assert.Equal(t, "user [REDACTED] logged in", entries[1].Message)
+	assert.Equal(t, "dni [REDACTED] and voter key [REDACTED] updated", entries[2].Message)
+	assert.Equal(t, "call [REDACTED]", entries[2].ContextMap()["note"])
+	assert.Equal(t, "phone [REDACTED] updated after 3 attempts, user 42", entries[3].Message)
+	assert.Equal(t, "phones [REDACTED] and [REDACTED] synced at 1712345678, 1048576000 bytes", entries[4].Message, "the bare numbers are kept")
+}
@@ -2,33 +2,51 @@ package middlewares
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/config"
 	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/apperror"
 	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/ratelimit"
 	"github.com/oaxacos/vitacare/pkg/response"
 	"github.com/oaxacos/vitacare/pkg/utils"
 )
 
-func AuthMiddleware(config *config.Config) func(next http.Handler) http.Handler {
+// UserChecker rejects the users that can't use their access tokens anymore,
+// the tokens stay valid until they expire.
+type UserChecker interface {
+	EnsureCanAuthenticate(ctx context.Context, id uuid.UUID) error
+}
+
+func AuthMiddleware(config *config.Config, users UserChecker) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			log := logger.GetContextLogger(r.Context())
 			authorizationToken := utils.GetAuthorizationToken(r)
 			if authorizationToken == "" {
 				log.Debugf("authorization token is empty")
-				response.RenderUnauthorized(w)
+				response.RenderUnauthorized(w, r)
 				return
 			}
 			claims, err := utils.VerifyAccessToken(authorizationToken, []byte(config.Token.PrivateKeyAccessToken))
 			if err != nil {
 				log.Errorf("error verifying access token: %s", err)
-				response.RenderUnauthorized(w)
+				response.RenderUnauthorized(w, r)
+				return
+			}
+			if err := users.EnsureCanAuthenticate(r.Context(), claims.UserID); err != nil {
+				log.Debugf("user %s can't authenticate: %s", claims.UserID, err)
+				response.RenderError(w, r, err)
 				return
 			}
 			newContext := context.WithValue(r.Context(), utils.AuthorizationKey, claims)
+			logger.AddRequestFields(newContext, "user_id", claims.UserID.String())
+			log = log.With("user_id", claims.UserID.String())
+			newContext = logger.SetContextLogger(newContext, log)
 			r = r.WithContext(newContext)
 			log.Debugf("user is authenticated")
 			next.ServeHTTP(w, r)
@@ -44,20 +62,20 @@ func AdminMiddleware(config *config.Config) func(next http.Handler) http.Handler
 			claims := utils.GetClaimsFromContext(r.Context())
 			if claims == nil {
 				log.Debugf("claims is nil")
-				response.RenderUnauthorized(w)
+				response.RenderUnauthorized(w, r)
 				return
 			}
 
 			userId := claims.UserID
 			if userId == uuid.Nil {
 				log.Debugf("user id is nil")
-				response.RenderUnauthorized(w)
+				response.RenderUnauthorized(w, r)
 				return
 			}
 
 			if claims.Rol != model.AdminRole {
 				log.Debugf("user is not admin, user role: %s", claims.Rol)
-				response.RenderForbidden(w)
+				response.RenderForbidden(w, r)
 				return
 			}
 
@@ -65,3 +83,120 @@ func AdminMiddleware(config *config.Config) func(next http.Handler) http.Handler
 		})
 	}
 }
+
+// RoleMiddleware only lets through users with one of the given roles.
+func RoleMiddleware(config *config.Config, roles ...model.UserRole) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := logger.GetContextLogger(r.Context())
+			claims := utils.GetClaimsFromContext(r.Context())
+			if claims == nil || claims.UserID == uuid.Nil {
+				log.Debugf("claims is nil")
+				response.RenderUnauthorized(w, r)
+				return
+			}
+
+			for _, role := range roles {
+				if claims.Rol == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			log.Debugf("user role %s is not allowed", claims.Rol)
+			response.RenderForbidden(w, r)
+		})
+	}
+}
+
+// APIKeyAuthenticator returns the active api key of the value sent by a
+// service account.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, value string) (*model.APIKey, error)
+}
+
+// APIKeyMiddleware authenticates the service accounts by the X-API-Key header,
+// the requests without it go through AuthMiddleware. The limit of the key,
+// when it has one, replaces the limit of the rate limit groups after it.
+func APIKeyMiddleware(config *config.Config, keys APIKeyAuthenticator, users UserChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		userAuth := AuthMiddleware(config, users)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value := r.Header.Get(utils.APIKeyHeader)
+			if value == "" {
+				userAuth.ServeHTTP(w, r)
+				return
+			}
+			log := logger.GetContextLogger(r.Context())
+			key, err := keys.Authenticate(r.Context(), value)
+			if err != nil {
+				response.RenderError(w, r, err)
+				return
+			}
+			ctx := utils.SetAPIKey(r.Context(), key)
+			if key.RateLimit > 0 {
+				ctx = ratelimit.WithLimit(ctx, ratelimit.Limit{Requests: key.RateLimit, Window: time.Minute})
+			}
+			logger.AddRequestFields(ctx, "service_account_id", key.ServiceAccountID.String())
+			log = log.With("service_account_id", key.ServiceAccountID.String(), "api_key_id", key.ID.String())
+			ctx = logger.SetContextLogger(ctx, log)
+			log.Debugf("service account is authenticated")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ScopeMiddleware lets through the api keys with scope and the users with
+// one of the given roles.
+func ScopeMiddleware(config *config.Config, scope model.Scope, roles ...model.UserRole) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		userRoles := RoleMiddleware(config, roles...)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := utils.GetAPIKeyFromContext(r.Context())
+			if key == nil {
+				userRoles.ServeHTTP(w, r)
+				return
+			}
+			if !key.HasScope(scope) {
+				logger.GetContextLogger(r.Context()).Debugf("api key is missing the scope %s", scope)
+				response.RenderForbidden(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaxBodySize sets the limit, in bytes, of the json bodies read by the routes
+// that use it, overriding the default of the server.
+func MaxBodySize(n int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(utils.SetMaxBodySize(r.Context(), n)))
+		})
+	}
+}
+
+var ErrInvalidCSRFToken = apperror.Forbidden("invalid_csrf_token", "missing or invalid csrf token")
+
+// CSRFMiddleware checks the double submit token of the requests authenticated
+// by the refresh token cookie: the X-CSRF-Token header must match the
+// csrf_token cookie, a cross site form can send the cookies but not read them.
+// The requests without the cookie are not checked, they can not be forged.
+func CSRFMiddleware(config *config.Config) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !config.Security.CSRF || utils.GetRefreshTokenFromCookie(r) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cookie, err := r.Cookie(response.CSRFCookieName)
+			header := r.Header.Get(response.CSRFHeader)
+			if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+				logger.GetContextLogger(r.Context()).Infof("invalid csrf token")
+				response.RenderError(w, r, ErrInvalidCSRFToken)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
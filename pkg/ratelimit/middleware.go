@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/metrics"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+)
+
+var ErrRateLimited = apperror.New(apperror.KindTooManyRequests, "rate_limited", "too many requests, retry in %d seconds")
+
+// KeyFunc returns the key the requests are counted by.
+type KeyFunc func(r *http.Request) string
+
+// ByIP counts the requests of every client address.
+func ByIP(r *http.Request) string {
+	ip := utils.GetClientIP(r.Context())
+	if ip == "" {
+		ip = utils.ClientIP(r)
+	}
+	return "ip:" + ip
+}
+
+// ByUser counts the requests of every authenticated user or api key, the
+// anonymous requests are counted by address.
+func ByUser(r *http.Request) string {
+	if claims := utils.GetClaimsFromContext(r.Context()); claims != nil {
+		return "user:" + claims.UserID.String()
+	}
+	if key := utils.GetAPIKeyFromContext(r.Context()); key != nil {
+		return "key:" + key.ID.String()
+	}
+	return ByIP(r)
+}
+
+// ByAPIKey counts the requests of every api key, the requests without one are
+// counted by address. The key is hashed so the stores never hold it.
+func ByAPIKey(r *http.Request) string {
+	key := r.Header.Get(utils.APIKeyHeader)
+	if key == "" {
+		return ByIP(r)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:])
+}
+
+var limitCtxKey = "ratelimit_limit"
+
+// WithLimit overrides the limit of the groups applied after it, like the
+// limit of an api key once it is authenticated.
+func WithLimit(ctx context.Context, limit Limit) context.Context {
+	return context.WithValue(ctx, limitCtxKey, limit)
+}
+
+func limitFromContext(ctx context.Context, limit Limit) Limit {
+	if override, ok := ctx.Value(limitCtxKey).(Limit); ok {
+		return override
+	}
+	return limit
+}
+
+// Policy is the limit of a group of routes.
+type Policy struct {
+	Limit Limit
+	Key   KeyFunc
+}
+
+// Limiter applies the policy of each group of routes, the policies can be
+// replaced while the server runs.
+type Limiter struct {
+	store    Store
+	policies atomic.Pointer[map[string]Policy]
+}
+
+func NewLimiter(store Store) *Limiter {
+	l := &Limiter{store: store}
+	l.SetPolicies(nil)
+	return l
+}
+
+// SetStore replaces the store, it must be called before serving requests.
+func (l *Limiter) SetStore(store Store) {
+	l.store = store
+}
+
+// SetPolicies replaces the policies of every group, the groups without one
+// are not limited.
+func (l *Limiter) SetPolicies(policies map[string]Policy) {
+	copied := make(map[string]Policy, len(policies))
+	for group, policy := range policies {
+		copied[group] = policy
+	}
+	l.policies.Store(&copied)
+}
+
+// Middleware limits the requests of the routes in group. When the store
+// fails the request is let through, an outage of the store should not take
+// the api down.
+func (l *Limiter) Middleware(group string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy, ok := (*l.policies.Load())[group]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			limit := limitFromContext(ctx, policy.Limit)
+			result, err := l.store.Allow(ctx, group+":"+policy.Key(r), limit)
+			if err != nil {
+				logger.GetContextLogger(ctx).Errorf("rate limit of %s: %s", group, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds())))
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter)
+				header.Set("Retry-After", strconv.Itoa(retryAfter))
+				logger.GetContextLogger(ctx).Infof("rate limit of %s exceeded", group)
+				metrics.IncRateLimited(group)
+				response.RenderError(w, r, ErrRateLimited.WithArgs(retryAfter))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
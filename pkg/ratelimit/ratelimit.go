@@ -0,0 +1,60 @@
+// Package ratelimit limits the requests a client can make in a window of
+// time. The limit is enforced with GCRA, a token bucket that only needs to
+// store one timestamp per key: the theoretical arrival time (tat) of the next
+// request. A client can burst the whole limit and then gets one request every
+// Window/Requests.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows Requests in every Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) interval() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+// Result is the decision for a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long the client has to wait, only set when the
+	// request is not allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the whole limit is available again
+	ResetAfter time.Duration
+}
+
+// Store keeps the state of every key, the stores shared by several instances
+// of the api must make Allow atomic.
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Next decides if a request made at now is allowed given the tat stored for
+// its key, it returns the tat to store. A zero tat is a key without requests.
+func Next(now, tat time.Time, limit Limit) (time.Time, Result) {
+	interval := limit.interval()
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	allowAt := next.Add(-limit.Window)
+	result := Result{Limit: limit.Requests}
+	if now.Before(allowAt) {
+		result.RetryAfter = allowAt.Sub(now)
+		result.ResetAfter = tat.Sub(now)
+		return tat, result
+	}
+	result.Allowed = true
+	result.Remaining = int(now.Sub(allowAt) / interval)
+	result.ResetAfter = next.Sub(now)
+	return next, result
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2025, 3, 29, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Window: 30 * time.Second}
+	ctx := context.Background()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := store.Allow(ctx, "a", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := store.Allow(ctx, "a", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 10*time.Second, result.RetryAfter)
+	assert.Equal(t, 30*time.Second, result.ResetAfter)
+
+	result, _ = store.Allow(ctx, "b", limit)
+	assert.True(t, result.Allowed, "the keys are counted apart")
+
+	now = now.Add(10 * time.Second)
+	result, _ = store.Allow(ctx, "a", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	now = now.Add(time.Hour)
+	store.Allow(ctx, "c", limit)
+	assert.Len(t, store.tats, 1, "the full keys are swept")
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore())
+	limiter.SetPolicies(map[string]Policy{
+		"auth": {Limit: Limit{Requests: 1, Window: time.Minute}, Key: ByIP},
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	send := func(group, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		recorder := httptest.NewRecorder()
+		limiter.Middleware(group)(ok).ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := send("auth", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", recorder.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "1;w=60", recorder.Header().Get("RateLimit-Policy"))
+
+	recorder = send("auth", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `"rate_limited"`)
+
+	assert.Equal(t, http.StatusOK, send("auth", "10.0.0.2").Code)
+	recorder = send("other", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, recorder.Code, "the groups without policy are not limited")
+	assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+}
+
+func TestByAPIKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "ip:10.0.0.1", ByAPIKey(req))
+
+	req.Header.Set(utils.APIKeyHeader, "secret")
+	key := ByAPIKey(req)
+	assert.NotContains(t, key, "secret")
+	assert.Equal(t, key, ByAPIKey(req))
+}
+
+func TestWithLimit(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore())
+	limiter.SetPolicies(map[string]Policy{
+		"api": {Limit: Limit{Requests: 1, Window: time.Minute}, Key: ByIP},
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/patients", nil)
+	req = req.WithContext(WithLimit(req.Context(), Limit{Requests: 5, Window: time.Minute}))
+	recorder := httptest.NewRecorder()
+	limiter.Middleware("api")(ok).ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "5;w=60", recorder.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "4", recorder.Header().Get("RateLimit-Remaining"))
+}
@@ -0,0 +1,120 @@
+package response
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/i18n"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/utils"
+)
+
+const problemContentType = "application/problem+json"
+
+var kindStatus = map[apperror.Kind]int{
+	apperror.KindNotFound:             http.StatusNotFound,
+	apperror.KindConflict:             http.StatusConflict,
+	apperror.KindValidation:           http.StatusBadRequest,
+	apperror.KindUnauthorized:         http.StatusUnauthorized,
+	apperror.KindForbidden:            http.StatusForbidden,
+	apperror.KindPayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	apperror.KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	apperror.KindTooManyRequests:      http.StatusTooManyRequests,
+	apperror.KindInternal:             http.StatusInternalServerError,
+}
+
+// Problem is the body of every error response, see RFC 7807. Code is the
+// machine-readable code of the error.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists the invalid fields of a validation problem.
+	Errors []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// StatusOf returns the http status of err, errors outside the taxonomy are
+// internal errors.
+func StatusOf(err error) int {
+	return kindStatus[toAppError(err).Kind]
+}
+
+func toAppError(err error) *apperror.Error {
+	if appErr, ok := apperror.As(err); ok {
+		return appErr
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.ErrNotFound.Wrap(err)
+	}
+	return apperror.ErrInternal.Wrap(err)
+}
+
+// newProblem translates the message of appErr to locale, the catalog is keyed
+// by the code of the error.
+func newProblem(appErr *apperror.Error, locale string) Problem {
+	status := kindStatus[appErr.Kind]
+	detail, ok := i18n.Message(locale, appErr.Code)
+	if !ok {
+		detail = appErr.Message
+	}
+	if len(appErr.Args) > 0 {
+		detail = fmt.Sprintf(detail, appErr.Args...)
+	}
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   appErr.Code,
+		Errors: appErr.Fields,
+	}
+}
+
+// RenderError maps err to a problem response in the language of the request.
+// Only the message of the error is sent, the cause is logged.
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := toAppError(err)
+	problem := newProblem(appErr, i18n.FromContext(r.Context()))
+	problem.Instance = r.URL.Path
+	problem.RequestID = utils.GetRequestID(r.Context())
+
+	log := logger.GetContextLogger(r.Context())
+	if problem.Status >= http.StatusInternalServerError {
+		log.Errorw(err.Error(), "code", problem.Code)
+	} else {
+		log.Debugw(err.Error(), "code", problem.Code)
+	}
+	writeProblem(w, problem)
+}
+
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	d, err := json.MarshalIndent(problem, "", "\t")
+	if err != nil {
+		logger.GetGlobalLogger().Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(d)
+}
+
+func RenderNotFound(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, apperror.ErrNotFound)
+}
+
+func RenderForbidden(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, apperror.ErrForbidden)
+}
+
+func RenderUnauthorized(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, apperror.ErrUnauthorized)
+}
@@ -0,0 +1,48 @@
+package response
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"domain error", apperror.Conflict("user_already_exists", "user already exist"), http.StatusConflict, "user_already_exists", "user already exist"},
+		{"no rows", sql.ErrNoRows, http.StatusNotFound, "not_found", "resource not found"},
+		{"internal details are hidden", errors.New("pq: connection refused"), http.StatusInternalServerError, "internal_error", "something went wrong"},
+		{"wrapped cause is hidden", apperror.ErrInvalidRequest.Wrap(errors.New("secret")), http.StatusBadRequest, "invalid_request", "invalid request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v0/users/1", nil)
+			w := httptest.NewRecorder()
+			RenderError(w, r, tt.err)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			var problem Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, Problem{
+				Type:     "about:blank",
+				Title:    http.StatusText(tt.status),
+				Status:   tt.status,
+				Detail:   tt.detail,
+				Instance: "/api/v0/users/1",
+				Code:     tt.code,
+			}, problem)
+		})
+	}
+}
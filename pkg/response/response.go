@@ -1,16 +1,20 @@
 package response
 
 import (
-	"database/sql"
 	"encoding/json"
-	"errors"
-	"github.com/oaxacos/vitacare/internal/application/dto"
-	"github.com/oaxacos/vitacare/pkg/logger"
 	"net/http"
+
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/i18n"
+	"github.com/oaxacos/vitacare/pkg/logger"
 )
 
 const (
-	refreshTokenCookieName = "refresh_token"
+	refreshTokenCookieName   = "refresh_token"
+	refreshTokenCookieMaxAge = 60 * 60 * 24 * 7
+
+	CSRFCookieName = "csrf_token"
+	CSRFHeader     = "X-CSRF-Token"
 )
 
 func WriteJsonResponse(w http.ResponseWriter, data any, status int) error {
@@ -28,90 +32,61 @@ func WriteJsonResponse(w http.ResponseWriter, data any, status int) error {
 func RenderJson(w http.ResponseWriter, data any, status int) {
 	err := WriteJsonResponse(w, data, status)
 	if err != nil {
-		RenderServerError(w, err)
+		logger.GetGlobalLogger().Error(err)
+		writeProblem(w, newProblem(apperror.ErrInternal, i18n.DefaultLocale))
 	}
 }
 
-func RenderError(w http.ResponseWriter, status int, message any) {
-	res := dto.MapResponseError(message, status)
-	log := logger.GetGlobalLogger()
-	err := WriteJsonResponse(w, res, status)
-
-	if err != nil {
-		log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-}
-
-func RenderServerError(w http.ResponseWriter, err error) {
-	logger.GetGlobalLogger().Error(err)
-	message := "something went wrong"
-	RenderError(w, http.StatusInternalServerError, message)
-}
-
-func RenderNotFound(w http.ResponseWriter) {
-	message := "page not found"
-	RenderError(w, http.StatusNotFound, message)
-}
-
-func RenderForbidden(w http.ResponseWriter) {
-	message := "forbidden"
-	RenderError(w, http.StatusForbidden, message)
+// CookieOptions are the attributes of the cookies the api sets, they come
+// from the security.cookie config.
+type CookieOptions struct {
+	Domain   string
+	Path     string
+	Secure   bool
+	SameSite http.SameSite
 }
 
-func RenderBadRequest(w http.ResponseWriter) {
-	message := "bad request"
-	RenderError(w, http.StatusBadRequest, message)
+// DefaultCookieOptions are the ones used before the cookies were configurable.
+var DefaultCookieOptions = CookieOptions{
+	Path:     "/",
+	Secure:   true,
+	SameSite: http.SameSiteNoneMode,
 }
 
-func RenderFatalError(w http.ResponseWriter, err error) {
-	if errors.Is(err, sql.ErrNoRows) {
-		RenderServerError(w, err)
-	} else {
-		RenderError(w, http.StatusBadRequest, err.Error())
+func (o CookieOptions) cookie(name, value string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   o.Domain,
+		Path:     o.Path,
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   o.Secure,
+		SameSite: o.SameSite,
 	}
 }
 
-func RenderUnauthorized(w http.ResponseWriter) {
-	message := "unauthorized"
-	RenderError(w, http.StatusUnauthorized, message)
+func DeleteCookie(w http.ResponseWriter, name string, opts CookieOptions) {
+	http.SetCookie(w, opts.cookie(name, "", -1, true))
 }
 
-type Cookie struct {
-	Name     string
-	Value    string
-	MaxAge   int
-	HttpOnly bool
-	Secure   bool
-	SameSite http.SameSite
+func SetRefreshTokenCookie(w http.ResponseWriter, token string, opts CookieOptions) {
+	http.SetCookie(w, opts.cookie(refreshTokenCookieName, token, refreshTokenCookieMaxAge, true))
 }
 
-func DeleteCookie(w http.ResponseWriter, name string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     name,
-		Value:    "",
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteNoneMode,
-	})
-}
-
-func SetRefreshTokenCookie(w http.ResponseWriter, token string) {
-	cookie := &http.Cookie{
-		Name:     refreshTokenCookieName,
-		Value:    token,
-		MaxAge:   60 * 60 * 24 * 7,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteNoneMode,
-	}
-	http.SetCookie(w, cookie)
+// SetCSRFCookie sets the token of the double submit check, scripts can read
+// it and send it back in the CSRFHeader. The token is also sent in the header
+// of the response for the frontends in another domain, they can not read the
+// cookie.
+func SetCSRFCookie(w http.ResponseWriter, token string, opts CookieOptions) {
+	http.SetCookie(w, opts.cookie(CSRFCookieName, token, refreshTokenCookieMaxAge, false))
+	w.Header().Set(CSRFHeader, token)
 }
 
-func DeleteRefreshTokenCookie(w http.ResponseWriter) {
-	DeleteCookie(w, refreshTokenCookieName)
+// DeleteRefreshTokenCookie also deletes the csrf cookie that protects it.
+func DeleteRefreshTokenCookie(w http.ResponseWriter, opts CookieOptions) {
+	DeleteCookie(w, refreshTokenCookieName, opts)
+	DeleteCookie(w, CSRFCookieName, opts)
 }
 
 func Envelop(key string, data any) map[string]any {
@@ -0,0 +1,37 @@
+package server
+
+import "github.com/go-chi/chi/v5"
+
+// Controller mounts its routes on the router of an api version, the versions
+// share the controller until one of them changes.
+type Controller interface {
+	Register(r chi.Router)
+}
+
+// VersionedController is a controller served only by some versions, the
+// other controllers are served by all of them.
+type VersionedController interface {
+	Controller
+	Versions() []string
+}
+
+// ControllerFunc is a Controller made of a function.
+type ControllerFunc func(r chi.Router)
+
+func (f ControllerFunc) Register(r chi.Router) {
+	f(r)
+}
+
+// Register mounts the routes of the controllers on the routers of their
+// versions.
+func (s *Server) Register(controllers ...Controller) {
+	for _, controller := range controllers {
+		versions := Versions
+		if versioned, ok := controller.(VersionedController); ok {
+			versions = versioned.Versions()
+		}
+		for _, version := range versions {
+			controller.Register(s.API(version))
+		}
+	}
+}
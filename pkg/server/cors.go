@@ -0,0 +1,169 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/oaxacos/vitacare/internal/config"
+)
+
+// corsPolicy is the cors config of a path, compiled for the middleware.
+type corsPolicy struct {
+	path             string
+	origins          []originPattern
+	allowedMethods   string
+	allowedHeaders   string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// originPattern is a trusted origin, a wildcard host matches the subdomains
+// but not the domain itself.
+type originPattern struct {
+	any      bool
+	scheme   string
+	host     string
+	wildcard bool
+}
+
+func parseOriginPattern(origin string) originPattern {
+	if origin == "*" {
+		return originPattern{any: true}
+	}
+	scheme, host, _ := strings.Cut(strings.ToLower(origin), "://")
+	pattern := originPattern{scheme: scheme, host: host}
+	if strings.HasPrefix(host, "*.") {
+		pattern.host = host[1:]
+		pattern.wildcard = true
+	}
+	return pattern
+}
+
+func (p originPattern) match(origin string) bool {
+	if p.any {
+		return true
+	}
+	scheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || scheme != p.scheme {
+		return false
+	}
+	if p.wildcard {
+		return strings.HasSuffix(host, p.host) && len(host) > len(p.host)
+	}
+	return host == p.host
+}
+
+// corsPolicies returns the policy of every route override, longest path
+// first, followed by the default policy.
+func corsPolicies(conf config.Cors) []corsPolicy {
+	base := newCorsPolicy("/", conf.TrustedOrigins, conf.AllowedMethods, conf.AllowedHeaders, conf.AllowCredentials, conf)
+	policies := make([]corsPolicy, 0, len(conf.Routes)+1)
+	for _, route := range conf.Routes {
+		origins, methods, headers := route.TrustedOrigins, route.AllowedMethods, route.AllowedHeaders
+		if len(origins) == 0 {
+			origins = conf.TrustedOrigins
+		}
+		if len(methods) == 0 {
+			methods = conf.AllowedMethods
+		}
+		if len(headers) == 0 {
+			headers = conf.AllowedHeaders
+		}
+		credentials := conf.AllowCredentials
+		if route.AllowCredentials != nil {
+			credentials = *route.AllowCredentials
+		}
+		policies = append(policies, newCorsPolicy(route.Path, origins, methods, headers, credentials, conf))
+	}
+	slices.SortStableFunc(policies, func(a, b corsPolicy) int {
+		return len(b.path) - len(a.path)
+	})
+	return append(policies, base)
+}
+
+func newCorsPolicy(path string, origins, methods, headers []string, credentials bool, conf config.Cors) corsPolicy {
+	policy := corsPolicy{
+		path:             strings.TrimSuffix(path, "/"),
+		allowedMethods:   strings.Join(methods, ", "),
+		allowedHeaders:   strings.Join(headers, ", "),
+		exposedHeaders:   strings.Join(conf.ExposedHeaders, ", "),
+		allowCredentials: credentials,
+	}
+	for _, origin := range origins {
+		policy.origins = append(policy.origins, parseOriginPattern(origin))
+	}
+	if conf.MaxAge > 0 {
+		policy.maxAge = strconv.Itoa(conf.MaxAge)
+	}
+	return policy
+}
+
+func (p *corsPolicy) allows(origin string) bool {
+	for _, pattern := range p.origins {
+		if pattern.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) corsPolicy(path string) *corsPolicy {
+	policies := *s.corsPolicies.Load()
+	for i := range policies {
+		// a route only covers the paths under its segments, /api/v0/users
+		// doesn't cover /api/v0/users-export
+		if path == policies[i].path || strings.HasPrefix(path, policies[i].path+"/") {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+// enableCors answers the preflight requests and allows the trusted origins
+// to read the responses. The allowed origin is echoed instead of *, so the
+// browsers can send the refresh token cookie.
+func (s *Server) enableCors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		origin := r.Header.Get("Origin")
+		policy := s.corsPolicy(r.URL.Path)
+		if origin == "" || policy == nil || !policy.allows(origin) {
+			if preflight && origin != "" {
+				// without the cors headers the browser blocks the request
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Origin", origin)
+		if policy.allowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if policy.exposedHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", policy.exposedHeaders)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", policy.allowedMethods)
+		header.Set("Access-Control-Allow-Headers", policy.allowedHeaders)
+		if policy.maxAge != "" {
+			header.Set("Access-Control-Max-Age", policy.maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
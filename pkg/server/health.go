@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+)
+
+const (
+	livenessPath  = "/livez"
+	readinessPath = "/readyz"
+)
+
+// handleLiveness only tells the process is alive, it never checks the
+// dependencies so a database outage doesn't restart every instance.
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	response.RenderJson(w, map[string]string{
+		"status": "ok",
+	}, http.StatusOK)
+}
+
+// handleReadiness runs the registered checks, admins get the result of every
+// check and everyone else only the overall status.
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	if !s.IsReady() {
+		response.RenderJson(w, map[string]string{
+			"status": "unavailable",
+		}, http.StatusServiceUnavailable)
+		return
+	}
+
+	report := s.Health.Run(r.Context())
+	status, code := "ok", http.StatusOK
+	if !report.Healthy() {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	if s.isAdmin(r) {
+		response.RenderJson(w, report, code)
+		return
+	}
+	response.RenderJson(w, map[string]string{
+		"status": status,
+	}, code)
+}
+
+func (s *Server) isAdmin(r *http.Request) bool {
+	token := utils.GetAuthorizationToken(r)
+	if token == "" {
+		return false
+	}
+	claims, err := utils.VerifyAccessToken(token, []byte(s.Config.Token.PrivateKeyAccessToken))
+	if err != nil {
+		return false
+	}
+	return claims.Rol == model.AdminRole
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/oaxacos/vitacare/pkg/i18n"
+)
+
+// localeMiddleware stores the language negotiated from Accept-Language in
+// the context, the messages of the response are translated to it.
+func localeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := i18n.Parse(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
+	})
+}
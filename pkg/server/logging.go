@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// requestIDMiddleware reuses the X-Request-ID sent by the client or a proxy,
+// or generates a new one, and sends it back in the response.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// loggerMiddleware stores a child logger with the request metadata in the
+// context and writes an access log line once the request is served.
+func loggerMiddleware(log *zap.SugaredLogger) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestLog := log.With(
+				"request_id", utils.GetRequestID(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+			)
+			ctx := logger.SetContextLogger(r.Context(), requestLog)
+			ctx = logger.WithRequestFields(ctx)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				fields := []any{
+					"route", routePattern(r),
+					"status", status,
+					"duration", time.Since(start),
+					"bytes", ww.BytesWritten(),
+					"remote_ip", utils.GetClientIP(ctx),
+				}
+				fields = append(fields, logger.GetRequestFields(ctx)...)
+				accessLog := requestLog.With(fields...)
+				switch {
+				case status >= http.StatusInternalServerError:
+					accessLog.Error("request completed")
+				case status >= http.StatusBadRequest:
+					accessLog.Warn("request completed")
+				default:
+					accessLog.Info("request completed")
+				}
+			}()
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// routePattern returns the chi pattern like /api/v0/users/{id}/role instead of
+// the path so the logs can be grouped by route.
+func routePattern(r *http.Request) string {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil {
+		return ""
+	}
+	return routeContext.RoutePattern()
+}
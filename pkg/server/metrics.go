@@ -0,0 +1,74 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/metrics"
+	"github.com/oaxacos/vitacare/pkg/response"
+)
+
+const (
+	metricsPath    = "/metrics"
+	unmatchedRoute = "unmatched"
+)
+
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		// never use the raw path as label, the ids would explode the cardinality
+		route := routePattern(r)
+		if route == "" || status == http.StatusNotFound {
+			route = unmatchedRoute
+		}
+		metrics.ObserveRequest(r.Method, route, status, time.Since(start))
+	})
+}
+
+// metricsHandler serves the prometheus metrics, protected with a bearer
+// token when one is configured.
+func metricsHandler(conf config.Metrics) http.Handler {
+	handler := metrics.Handler()
+	if conf.Token == "" {
+		return handler
+	}
+	expected := []byte("Bearer " + conf.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			response.RenderUnauthorized(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) startMetricsServer() {
+	logs := logger.GetGlobalLogger()
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, metricsHandler(s.Config.Server.Metrics))
+	s.metricsServer = &http.Server{
+		Addr:              ":" + strconv.Itoa(s.Config.Server.Metrics.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+	}
+	logs.Infof("start metrics server on %s", s.metricsServer.Addr)
+	go func() {
+		err := s.metricsServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logs.Errorf("metrics server stopped: %s", err)
+		}
+	}()
+}
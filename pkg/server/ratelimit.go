@@ -0,0 +1,35 @@
+package server
+
+import (
+	"time"
+
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/ratelimit"
+)
+
+var rateLimitKeys = map[string]ratelimit.KeyFunc{
+	"ip":      ratelimit.ByIP,
+	"user":    ratelimit.ByUser,
+	"api-key": ratelimit.ByAPIKey,
+}
+
+func rateLimitPolicies(conf config.RateLimit) map[string]ratelimit.Policy {
+	if !conf.Enabled {
+		return nil
+	}
+	policies := make(map[string]ratelimit.Policy, len(conf.Groups))
+	for name, group := range conf.Groups {
+		key, ok := rateLimitKeys[group.Key]
+		if !ok {
+			key = ratelimit.ByIP
+		}
+		policies[name] = ratelimit.Policy{
+			Limit: ratelimit.Limit{
+				Requests: group.Requests,
+				Window:   time.Duration(group.Window) * time.Second,
+			},
+			Key: key,
+		}
+	}
+	return policies
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/response"
+)
+
+var sameSiteModes = map[string]http.SameSite{
+	"strict": http.SameSiteStrictMode,
+	"lax":    http.SameSiteLaxMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// cookieOptions returns the attributes of the cookies set by the controllers.
+func cookieOptions(conf config.Cookie) response.CookieOptions {
+	sameSite, ok := sameSiteModes[conf.SameSite]
+	if !ok {
+		sameSite = response.DefaultCookieOptions.SameSite
+	}
+	return response.CookieOptions{
+		Domain:   conf.Domain,
+		Path:     conf.Path,
+		Secure:   conf.Secure,
+		SameSite: sameSite,
+	}
+}
+
+// securityHeaders sets the headers that tell the browsers to not sniff,
+// frame or leak the responses of the api.
+func securityHeaders(conf config.Security) func(next http.Handler) http.Handler {
+	hsts := ""
+	if conf.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(conf.HSTSMaxAge) + "; includeSubDomains"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			if hsts != "" {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			if conf.FrameOptions != "" {
+				header.Set("X-Frame-Options", conf.FrameOptions)
+			}
+			if conf.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", conf.ReferrerPolicy)
+			}
+			// the swagger ui runs scripts and styles of its own
+			if conf.ContentSecurityPolicy != "" && !strings.HasPrefix(r.URL.Path, "/swagger/") {
+				header.Set("Content-Security-Policy", conf.ContentSecurityPolicy)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,13 +1,20 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"sync/atomic"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/health"
 	"github.com/oaxacos/vitacare/pkg/logger"
+	"github.com/oaxacos/vitacare/pkg/middlewares"
+	"github.com/oaxacos/vitacare/pkg/ratelimit"
 	"github.com/oaxacos/vitacare/pkg/response"
-	httpSwagger "github.com/swaggo/http-swagger"
-	"go.uber.org/zap"
+	"github.com/oaxacos/vitacare/pkg/utils"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,103 +22,216 @@ import (
 
 var defaultPort = ":8080"
 
+const (
+	defaultReadTimeout       = 15 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+	defaultShutdownTimeout   = 20 * time.Second
+)
+
 type Server struct {
 	*chi.Mux
 	Config *config.Config
+	// Health holds the checks run by the readiness probe
+	Health *health.Registry
+	// Cookies are the attributes of the cookies set by the controllers
+	Cookies response.CookieOptions
+	// RateLimiter limits the route groups configured in ratelimit.groups
+	RateLimiter *ratelimit.Limiter
+	// ready is false while the server shuts down, so the load balancer stops
+	// sending traffic before the connections are drained
+	ready atomic.Bool
+	// versions are the routers of every api version
+	versions map[string]chi.Router
+	// corsPolicies is replaced on a config reload
+	corsPolicies  atomic.Pointer[[]corsPolicy]
+	handler       http.Handler
+	httpServer    *http.Server
+	metricsServer *http.Server
 }
 
-func handleHealthcheck(w http.ResponseWriter, r *http.Request) {
-	response.RenderJson(w, map[string]string{
-		"status": "ok",
-	}, http.StatusOK)
-}
+var errRouteNotFound = apperror.NotFound("route_not_found", "page not found")
 
 func handleNotFound(w http.ResponseWriter, r *http.Request) {
-	response.RenderNotFound(w)
+	response.RenderError(w, r, errRouteNotFound)
 }
 
 func handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
-	response.RenderNotFound(w)
+	response.RenderError(w, r, errRouteNotFound)
 }
 
 func NewServer(conf *config.Config) *Server {
 	logs := logger.GetGlobalLogger()
+	s := &Server{
+		Config: conf,
+		Health: health.NewRegistry(
+			seconds(conf.Server.Health.CheckTimeout, 0),
+			seconds(conf.Server.Health.CacheTTL, 0),
+		),
+		Cookies:     cookieOptions(conf.Security.Cookie),
+		RateLimiter: ratelimit.NewLimiter(ratelimit.NewMemoryStore()),
+	}
 	r := chi.NewRouter()
+	r.Use(requestIDMiddleware)
+	r.Use(clientIPMiddleware)
 	r.Use(loggerMiddleware(logs))
-	r.Use(enableCors(conf))
+	r.Use(localeMiddleware)
+	r.Use(tracingMiddleware)
+	r.Use(metricsMiddleware)
+	r.Use(securityHeaders(conf.Security))
+	r.Use(s.enableCors)
+	if conf.Server.MaxBodySize > 0 {
+		// the default limit of the json bodies, the routes can override it
+		r.Use(middlewares.MaxBodySize(int64(conf.Server.MaxBodySize) << 10))
+	}
+	if conf.Server.RequestTimeout > 0 {
+		r.Use(middleware.Timeout(seconds(conf.Server.RequestTimeout, 0)))
+	}
+
+	r.Get(livenessPath, s.handleLiveness)
+	r.Get(readinessPath, s.handleReadiness)
 
-	r.Get("/api/v0/healthcheck", handleHealthcheck)
+	metricsConf := conf.Server.Metrics
+	if metricsConf.Enabled && metricsConf.Port == 0 {
+		r.Handle(metricsPath, metricsHandler(metricsConf))
+	}
 
-	r.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("swagger/doc.json"), //The url pointing to API definition
-	))
+	s.mountVersions(r, conf.Server.Versions)
+	// kept for the clients that still use it, it behaves like the readiness probe
+	s.API(V0).Get("/healthcheck", s.handleReadiness)
 
 	r.NotFound(handleNotFound)
 	r.MethodNotAllowed(handleMethodNotAllowed)
 
-	return &Server{
-		r,
-		conf,
-	}
+	s.Mux = r
+	s.handler = r
+	s.ready.Store(true)
+	s.Reload(conf)
+	return s
 }
 
-func (s *Server) Start() error {
-	logs := logger.GetGlobalLogger()
-	port := defaultPort
-	portStr := strconv.Itoa(s.Config.Server.Port)
-	if portStr != "" {
-		port = ":" + portStr
+// Reload applies the settings that can change without a restart.
+func (s *Server) Reload(conf *config.Config) {
+	policies := corsPolicies(conf.Cors)
+	s.corsPolicies.Store(&policies)
+	s.RateLimiter.SetPolicies(rateLimitPolicies(conf.RateLimit))
+}
+
+// RateLimit limits the requests of the routes in group, it does nothing when
+// the group has no limit.
+func (s *Server) RateLimit(group string) func(next http.Handler) http.Handler {
+	return s.RateLimiter.Middleware(group)
+}
+
+func seconds(value int, defaultValue time.Duration) time.Duration {
+	if value <= 0 {
+		return defaultValue
 	}
-	logs.Infof("start server on %s", port)
-	return http.ListenAndServe(port, s.Mux)
+	return time.Duration(value) * time.Second
 }
 
-func (s *Server) StartWithTimeout(timeout time.Duration) error {
-	logs := logger.GetGlobalLogger()
+func (s *Server) IsReady() bool {
+	return s.ready.Load()
+}
+
+func (s *Server) address() string {
 	port := defaultPort
-	portStr := strconv.Itoa(s.Config.Server.Port)
-	if portStr != "" {
-		port = ":" + portStr
+	if s.Config.Server.Port != 0 {
+		port = ":" + strconv.Itoa(s.Config.Server.Port)
 	}
-	logs.Infof("start server on %s", port)
-	logs.Infof("start timeout %d", timeout)
-
-	s.Group(func(r chi.Router) {
-		r.Use(middleware.Timeout(timeout))
+	return port
+}
 
-	})
+// Start serves the api until Shutdown is called, it returns nil when the
+// server was shut down.
+func (s *Server) Start() error {
+	s.prepare()
+	return s.serve()
+}
 
-	return http.ListenAndServe(port, s.Mux)
+func (s *Server) prepare() {
+	conf := s.Config.Server
+	s.httpServer = &http.Server{
+		Addr:              s.address(),
+		Handler:           s.handler,
+		ReadTimeout:       seconds(conf.ReadTimeout, defaultReadTimeout),
+		ReadHeaderTimeout: seconds(conf.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		WriteTimeout:      seconds(conf.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       seconds(conf.IdleTimeout, defaultIdleTimeout),
+	}
+	if conf.Metrics.Enabled && conf.Metrics.Port != 0 {
+		s.startMetricsServer()
+	}
 }
 
-func loggerMiddleware(log *zap.SugaredLogger) func(handler http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := logger.SetContextLogger(r.Context(), log)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
+func (s *Server) serve() error {
+	logger.GetGlobalLogger().Infof("start server on %s", s.httpServer.Addr)
+	err := s.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
 	}
+	return err
+}
+
+// StartWithTimeout is like Start but cancels the requests that take longer
+// than timeout.
+func (s *Server) StartWithTimeout(timeout time.Duration) error {
+	logger.GetGlobalLogger().Infof("start timeout %s", timeout)
+	s.handler = middleware.Timeout(timeout)(s.Mux)
+	return s.Start()
 }
 
-func enableCors(conf *config.Config) func(next http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-			if origin != "" {
-				for i := range conf.Cors.TrustedOrigins {
-					if origin == conf.Cors.TrustedOrigins[i] {
-						w.Header().Set("Access-Control-Allow-Origin", origin)
-						if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
-							w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, PATCH, DELETE")
-							w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-							w.WriteHeader(http.StatusOK)
-						}
-						break
-					}
-				}
-			}
-			next.ServeHTTP(w, r)
-		})
+// Run starts the server and shuts it down gracefully once ctx is done, like
+// on SIGINT or SIGTERM.
+func (s *Server) Run(ctx context.Context) error {
+	s.prepare()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.serve()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logs := logger.GetGlobalLogger()
+	logs.Info("shutting down server")
+	// the load balancer needs a few probes to notice the server is not
+	// ready, the requests it sends until then are still served
+	s.ready.Store(false)
+	if delay := time.Duration(s.Config.Server.DrainDelay) * time.Second; delay > 0 {
+		logs.Infof("waiting %s before draining the connections", delay)
+		time.Sleep(delay)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), seconds(s.Config.Server.ShutdownTimeout, defaultShutdownTimeout))
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	return <-errCh
+}
 
+// Shutdown marks the server as not ready and waits for the in-flight
+// requests to finish.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.ready.Store(false)
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			logger.GetGlobalLogger().Error(err)
+		}
+	}
+	if s.httpServer == nil {
+		return nil
 	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+func clientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := utils.SetClientIP(r.Context(), utils.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
@@ -1,14 +1,28 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/oaxacos/vitacare/internal/application/dto"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/health"
+	"github.com/oaxacos/vitacare/pkg/response"
+	"github.com/oaxacos/vitacare/pkg/utils"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 var conf = &config.Config{
@@ -41,19 +55,345 @@ func TestServer(t *testing.T) {
 
 func TestServerNotFound(t *testing.T) {
 	s := NewServer(conf)
-	var notFound = dto.MapResponseError("page not found", http.StatusNotFound)
 	req, err := http.NewRequest("GET", "/api/v0/healthcheck/test", nil)
 	assert.NoError(t, err)
 
 	recorder := httptest.NewRecorder()
 
 	s.ServeHTTP(recorder, req)
-	var receivedMessage dto.ErrorResponse
+	var receivedMessage response.Problem
 	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
 
 	err = json.Unmarshal(recorder.Body.Bytes(), &receivedMessage)
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equalf(t, notFound, receivedMessage, "expected %v but got %v", notFound, receivedMessage)
+	assert.Equal(t, http.StatusNotFound, receivedMessage.Status)
+	assert.Equal(t, "route_not_found", receivedMessage.Code)
+	assert.Equal(t, "page not found", receivedMessage.Detail)
+	assert.Equal(t, "/api/v0/healthcheck/test", receivedMessage.Instance)
+	assert.NotEmpty(t, receivedMessage.RequestID)
+}
+
+func TestServerRequestID(t *testing.T) {
+	s := NewServer(conf)
+
+	t.Run("reuses the request id of the client", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/api/v0/healthcheck", nil)
+		assert.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "abc-123")
+
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		assert.Equal(t, "abc-123", recorder.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("generates a request id", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/api/v0/healthcheck", nil)
+		assert.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "invalid id\n")
+
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		assert.NotEmpty(t, recorder.Header().Get(RequestIDHeader))
+		assert.NotEqual(t, "invalid id\n", recorder.Header().Get(RequestIDHeader))
+	})
+}
+
+func TestServerMetrics(t *testing.T) {
+	metricsConf := &config.Config{
+		Server: config.Server{
+			Metrics: config.Metrics{
+				Enabled: true,
+				Token:   "secret",
+			},
+		},
+	}
+	s := NewServer(metricsConf)
+
+	req, err := http.NewRequest("GET", "/api/v0/healthcheck", nil)
+	assert.NoError(t, err)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, err = http.NewRequest("GET", "/metrics", nil)
+	assert.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `vitacare_http_requests_total{method="GET",route="/api/v0/healthcheck",status="200"}`)
+}
+
+func TestServerTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	s := NewServer(conf)
+	req, err := http.NewRequest("GET", "/api/v0/healthcheck", nil)
+	assert.NoError(t, err)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/v0/healthcheck", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
+
+func TestServerShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.NoError(t, listener.Close())
+
+	s := NewServer(&config.Config{Server: config.Server{Port: port, DrainDelay: 1}})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/v0/healthcheck", port)
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	// during the drain delay the server fails the probes but still serves
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, <-done)
+	assert.False(t, s.IsReady())
+
+	req, err := http.NewRequest("GET", "/api/v0/healthcheck", nil)
+	assert.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestServerProbes(t *testing.T) {
+	probeConf := &config.Config{
+		Token: config.Token{PrivateKeyAccessToken: "probe-secret"},
+	}
+	s := NewServer(probeConf)
+	s.Health.Register(health.Check{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			return errors.New("connection refused")
+		},
+	})
+
+	req, err := http.NewRequest("GET", "/livez", nil)
+	assert.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	req, err = http.NewRequest("GET", "/readyz", nil)
+	assert.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "connection refused")
+
+	admin := &model.User{ID: uuid.New(), Rol: model.AdminRole}
+	token, err := utils.GenerateAccessToken(admin, time.Minute, []byte("probe-secret"))
+	assert.NoError(t, err)
+	req, err = http.NewRequest("GET", "/readyz", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Len(t, report.Checks, 1)
+	assert.Equal(t, "connection refused", report.Checks[0].Error)
+}
+
+func TestServerLocale(t *testing.T) {
+	s := NewServer(conf)
+	req, err := http.NewRequest("GET", "/api/v0/healthcheck", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Accept-Language", "es-MX,es;q=0.9,en;q=0.8")
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, "es", recorder.Header().Get("Content-Language"))
+	assert.Contains(t, recorder.Header().Values("Vary"), "Accept-Language")
+}
+
+func TestServerCors(t *testing.T) {
+	public := false
+	corsConf := &config.Config{
+		Cors: config.Cors{
+			TrustedOrigins:   []string{"https://vitacare.example", "https://*.vitacare.example"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowedHeaders:   []string{"Authorization", "Content-Type"},
+			ExposedHeaders:   []string{RequestIDHeader},
+			AllowCredentials: true,
+			MaxAge:           600,
+			Routes: []config.CorsRoute{
+				{Path: "/api/v0/healthcheck", TrustedOrigins: []string{"*"}, AllowCredentials: &public},
+			},
+		},
+	}
+	s := NewServer(corsConf)
+	s.Get("/api/v0/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	s.Get("/api/v0/healthcheck-internal", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	send := func(method, path, origin string, preflight bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+		}
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("answers the preflight", func(t *testing.T) {
+		recorder := send(http.MethodOptions, "/api/v0/users", "https://vitacare.example", true)
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, "https://vitacare.example", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "GET, POST", recorder.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Authorization, Content-Type", recorder.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+		assert.Contains(t, recorder.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("allows the subdomains", func(t *testing.T) {
+		recorder := send(http.MethodGet, "/api/v0/users", "https://app.vitacare.example", false)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "https://app.vitacare.example", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, RequestIDHeader, recorder.Header().Get("Access-Control-Expose-Headers"))
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("rejects the other origins", func(t *testing.T) {
+		for _, origin := range []string{"https://evil.example", "http://vitacare.example", "https://vitacare.example.evil"} {
+			recorder := send(http.MethodOptions, "/api/v0/users", origin, true)
+			assert.Equal(t, http.StatusNoContent, recorder.Code, origin)
+			assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"), origin)
+		}
+		recorder := send(http.MethodGet, "/api/v0/users", "https://evil.example", false)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("overrides the routes", func(t *testing.T) {
+		recorder := send(http.MethodGet, "/api/v0/healthcheck", "https://evil.example", false)
+		assert.Equal(t, "https://evil.example", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+
+		recorder = send(http.MethodGet, "/api/v0/healthcheck/", "https://evil.example", false)
+		assert.Equal(t, "https://evil.example", recorder.Header().Get("Access-Control-Allow-Origin"), "the paths under the route")
+		recorder = send(http.MethodGet, "/api/v0/healthcheck-internal", "https://evil.example", false)
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"), "the route ends at a segment")
+	})
+}
+
+func TestServerSecurityHeaders(t *testing.T) {
+	s := NewServer(&config.Config{
+		Security: config.Security{
+			HSTSMaxAge:            600,
+			ContentSecurityPolicy: "default-src 'none'",
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v0/healthcheck", nil)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, "max-age=600; includeSubDomains", recorder.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'none'", recorder.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", recorder.Header().Get("Referrer-Policy"))
+
+	req = httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil)
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Empty(t, recorder.Header().Get("Content-Security-Policy"), "the swagger ui loads scripts")
+}
+
+func TestServerVersions(t *testing.T) {
+	s := NewServer(&config.Config{
+		Server: config.Server{
+			Versions: map[string]config.APIVersion{
+				V0: {Deprecated: "2025-04-01", Sunset: "2025-10-01", Link: "https://vitacare.example/v1"},
+			},
+		},
+	})
+	s.Register(ControllerFunc(func(r chi.Router) {
+		r.Get("/ping/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	recorder := send("/api/v0/ping/")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "@1743465600", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 01 Oct 2025 00:00:00 GMT", recorder.Header().Get("Sunset"))
+	assert.Equal(t, `<https://vitacare.example/v1>; rel="deprecation"`, recorder.Header().Get("Link"))
+
+	recorder = send("/api/v1/ping/")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Deprecation"))
+
+	assert.Equal(t, http.StatusNotFound, send("/api/v1/healthcheck").Code, "the legacy healthcheck is only in v0")
+	assert.Equal(t, http.StatusNotFound, send("/api/v2/ping/").Code)
+	assert.Panics(t, func() { s.API("v2") })
+
+	s.Register(v1Controller{})
+	assert.Equal(t, http.StatusOK, send("/api/v1/new").Code)
+	assert.Equal(t, http.StatusNotFound, send("/api/v0/new").Code, "the versioned controllers are only in their versions")
+}
+
+type v1Controller struct{}
+
+func (v1Controller) Register(r chi.Router) {
+	r.Get("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (v1Controller) Versions() []string {
+	return []string{V1}
 }
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/oaxacos/vitacare/internal/config"
+	"github.com/oaxacos/vitacare/pkg/logger"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+const (
+	V0 = "v0"
+	V1 = "v1"
+)
+
+// Versions are the versions of the api served, the newest last. Every version
+// is mounted on /api/<version> and has its own swagger docs.
+var Versions = []string{V0, V1}
+
+// LatestVersion is the version of the swagger docs served on /swagger/.
+var LatestVersion = Versions[len(Versions)-1]
+
+// API returns the router of version, the routes of a deprecated version send
+// the Deprecation and Sunset headers.
+func (s *Server) API(version string) chi.Router {
+	router, ok := s.versions[version]
+	if !ok {
+		panic(fmt.Sprintf("api version %s does not exist", version))
+	}
+	return router
+}
+
+func (s *Server) mountVersions(r chi.Router, conf map[string]config.APIVersion) {
+	logs := logger.GetGlobalLogger()
+	for version := range conf {
+		if !slices.Contains(Versions, version) {
+			logs.Warnf("server.versions.%s is not a version of the api", version)
+		}
+	}
+	s.versions = make(map[string]chi.Router, len(Versions))
+	for _, version := range Versions {
+		router := chi.NewRouter()
+		if deprecation, ok := conf[version]; ok {
+			router.Use(deprecated(version, deprecation))
+			logs.Infof("api %s is deprecated since %s", version, deprecation.Deprecated)
+		}
+		r.Mount("/api/"+version, router)
+		r.Get("/swagger/"+version+"/*", httpSwagger.Handler(httpSwagger.InstanceName(version)))
+		s.versions[version] = router
+	}
+	r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.InstanceName(LatestVersion)))
+}
+
+func deprecated(version string, conf config.APIVersion) func(next http.Handler) http.Handler {
+	// the dates are checked by the config validation
+	deprecation, _ := time.Parse(time.DateOnly, conf.Deprecated)
+	var sunset time.Time
+	if conf.Sunset != "" {
+		sunset, _ = time.Parse(time.DateOnly, conf.Sunset)
+	}
+	return Deprecated(deprecation, sunset, conf.Link)
+}
+
+// Deprecated marks the routes as deprecated since deprecation, see RFC 9745,
+// and removed at sunset, see RFC 8594, when it is not zero. The link
+// documents the migration.
+func Deprecated(deprecation, sunset time.Time, link string) func(next http.Handler) http.Handler {
+	deprecationHeader := "@" + strconv.FormatInt(deprecation.Unix(), 10)
+	sunsetHeader := ""
+	if !sunset.IsZero() {
+		sunsetHeader = sunset.UTC().Format(http.TimeFormat)
+	}
+	linkHeader := ""
+	if link != "" {
+		linkHeader = fmt.Sprintf("<%s>; rel=\"deprecation\"", link)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("Deprecation", deprecationHeader)
+			if sunsetHeader != "" {
+				header.Set("Sunset", sunsetHeader)
+			}
+			if linkHeader != "" {
+				header.Add("Link", linkHeader)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
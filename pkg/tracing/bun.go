@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const maxStatementLength = 2048
+
+// QueryHook creates a span for every bun query.
+type QueryHook struct {
+	dbName string
+}
+
+var _ bun.QueryHook = (*QueryHook)(nil)
+
+func NewQueryHook(dbName string) *QueryHook {
+	return &QueryHook{dbName: dbName}
+}
+
+func (h *QueryHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx
+	}
+	ctx, _ = Start(ctx, "db."+event.Operation(), trace.WithSpanKind(trace.SpanKindClient))
+	return ctx
+}
+
+func (h *QueryHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	defer span.End()
+
+	statement := event.Query
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength]
+	}
+	span.SetAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBNamespace(h.dbName),
+		semconv.DBOperationName(event.Operation()),
+		attribute.String("db.query.text", statement),
+	)
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+}
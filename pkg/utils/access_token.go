@@ -2,7 +2,8 @@ package utils
 
 import (
 	"context"
-	"errors"
+	"crypto/rand"
+	"encoding/base64"
 	"net/http"
 	"strings"
 	"time"
@@ -10,11 +11,12 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/oaxacos/vitacare/internal/domain/model"
+	"github.com/oaxacos/vitacare/pkg/apperror"
 	"github.com/oaxacos/vitacare/pkg/logger"
 )
 
 var (
-	ErrorInvalidToken = errors.New("invalid token")
+	ErrorInvalidToken = apperror.Unauthorized("invalid_token", "invalid token")
 	AuthorizationKey  = "authorization"
 	RefreshTokenKey   = "refresh_token"
 )
@@ -99,3 +101,12 @@ func GetRefreshTokenFromCookie(r *http.Request) string {
 	}
 	return cookie.Value
 }
+
+// GenerateCSRFToken returns the random token of the double submit check.
+func GenerateCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
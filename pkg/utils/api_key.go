@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oaxacos/vitacare/internal/domain/model"
+)
+
+// APIKeyHeader carries the key of the service accounts.
+const APIKeyHeader = "X-API-Key"
+
+var APIKeyCtxKey = "api_key"
+
+func SetAPIKey(ctx context.Context, key *model.APIKey) context.Context {
+	return context.WithValue(ctx, APIKeyCtxKey, key)
+}
+
+// GetAPIKeyFromContext returns the key the request was authenticated with,
+// nil when it was authenticated by a user.
+func GetAPIKeyFromContext(ctx context.Context) *model.APIKey {
+	key, ok := ctx.Value(APIKeyCtxKey).(*model.APIKey)
+	if !ok {
+		return nil
+	}
+	return key
+}
+
+// GetActorID returns the authenticated user or the service account of the
+// api key, uuid.Nil for the anonymous requests.
+func GetActorID(ctx context.Context) uuid.UUID {
+	if claims := GetClaimsFromContext(ctx); claims != nil {
+		return claims.UserID
+	}
+	if key := GetAPIKeyFromContext(ctx); key != nil {
+		return key.ServiceAccountID
+	}
+	return uuid.Nil
+}
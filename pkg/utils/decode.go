@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/oaxacos/vitacare/pkg/apperror"
+)
+
+// DefaultMaxBodySize is the limit of the request bodies when neither the
+// server nor the route set one.
+const DefaultMaxBodySize int64 = 1 << 20
+
+var MaxBodySizeKey = "max_body_size"
+
+var (
+	ErrorBodyReadAfterClose   = apperror.Validation("body_closed", "request body closed")
+	ErrorEmptyRequestBody     = apperror.Validation("empty_body", "empty request body")
+	ErrorInvalidBodyRequest   = apperror.Validation("invalid_body", "invalid request body")
+	ErrorTrailingData         = apperror.Validation("trailing_data", "request body must contain a single json value")
+	ErrorUnknownField         = apperror.Validation("unknown_field", "unknown field %s")
+	ErrorInvalidFieldType     = apperror.Validation("invalid_field_type", "field %s must be of type %s")
+	ErrorBodyTooLarge         = apperror.New(apperror.KindPayloadTooLarge, "body_too_large", "request body is larger than %d bytes")
+	ErrorUnsupportedMediaType = apperror.New(apperror.KindUnsupportedMediaType, "unsupported_media_type", "content type must be application/json")
+)
+
+type decodeOptions struct {
+	allowUnknownFields bool
+	maxBodySize        int64
+}
+
+type DecodeOption func(*decodeOptions)
+
+// AllowUnknownFields ignores the fields of the body that data doesn't have,
+// by default they are rejected.
+func AllowUnknownFields() DecodeOption {
+	return func(o *decodeOptions) {
+		o.allowUnknownFields = true
+	}
+}
+
+// WithMaxBodySize overrides the limit of the route for a single decode.
+func WithMaxBodySize(n int64) DecodeOption {
+	return func(o *decodeOptions) {
+		o.maxBodySize = n
+	}
+}
+
+func SetMaxBodySize(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, MaxBodySizeKey, n)
+}
+
+// GetMaxBodySize returns the limit of the request bodies set by the server or
+// the route.
+func GetMaxBodySize(ctx context.Context) int64 {
+	n, ok := ctx.Value(MaxBodySizeKey).(int64)
+	if !ok || n <= 0 {
+		return DefaultMaxBodySize
+	}
+	return n
+}
+
+// ReadFromRequest decodes the json body of r into data, a pointer. The body
+// must be a single json value of the allowed size sent as application/json,
+// and it can't have fields data doesn't know unless AllowUnknownFields is
+// used.
+func ReadFromRequest(r *http.Request, data any, opts ...DecodeOption) error {
+	options := decodeOptions{
+		maxBodySize: GetMaxBodySize(r.Context()),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		return ErrorUnsupportedMediaType
+	}
+	return decodeJSON(http.MaxBytesReader(nil, r.Body, options.maxBodySize), data, options)
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "application/json" {
+		return false
+	}
+	charset, ok := params["charset"]
+	return !ok || strings.EqualFold(charset, "utf-8")
+}
+
+func decodeJSON(body io.Reader, data any, options decodeOptions) error {
+	decoder := json.NewDecoder(body)
+	if !options.allowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(data)
+	if err != nil {
+		return decodeError(err, options)
+	}
+	// anything after the value is an error, even another valid value
+	err = decoder.Decode(&json.RawMessage{})
+	if !errors.Is(err, io.EOF) {
+		if err != nil {
+			return decodeError(err, options)
+		}
+		return ErrorTrailingData
+	}
+	return nil
+}
+
+func decodeError(err error, options decodeOptions) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrorEmptyRequestBody
+	case errors.Is(err, http.ErrBodyReadAfterClose):
+		return ErrorBodyReadAfterClose
+	case errors.As(err, &maxBytesErr):
+		return ErrorBodyTooLarge.WithArgs(options.maxBodySize)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorInvalidBodyRequest.Wrap(err)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return ErrorInvalidBodyRequest.Wrap(err)
+		}
+		return ErrorInvalidFieldType.WithArgs(typeErr.Field, jsonType(typeErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// the decoder has no typed error for unknown fields
+		field, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		if unquoteErr != nil {
+			return ErrorInvalidBodyRequest.Wrap(err)
+		}
+		return ErrorUnknownField.WithArgs(field)
+	}
+	return ErrorInvalidBodyRequest.Wrap(err)
+}
+
+// jsonType names the json type of a go type for the error messages.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	ZipCode string `json:"zip_code"`
+}
+
+type patient struct {
+	Name    string   `json:"name"`
+	Age     int      `json:"age"`
+	Tags    []string `json:"tags"`
+	Address address  `json:"address"`
+}
+
+func newJSONRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return r
+}
+
+func TestReadFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		err     error
+		message string
+	}{
+		{"valid", `{"name": "Ana", "age": 30, "address": {"zip_code": "68000"}}`, nil, ""},
+		{"empty", ``, ErrorEmptyRequestBody, "empty request body"},
+		{"syntax", `{"name": `, ErrorInvalidBodyRequest, ""},
+		{"unknown field", `{"name": "Ana", "admin": true}`, ErrorUnknownField, "unknown field admin"},
+		{"type mismatch", `{"age": "thirty"}`, ErrorInvalidFieldType, "field age must be of type number"},
+		{"nested type mismatch", `{"address": {"zip_code": 68000}}`, ErrorInvalidFieldType, "field address.zip_code must be of type string"},
+		{"trailing value", `{"name": "Ana"} {"name": "Bob"}`, ErrorTrailingData, ""},
+		{"trailing garbage", `{"name": "Ana"} x`, ErrorInvalidBodyRequest, ""},
+		{"not an object", `["Ana"]`, ErrorInvalidBodyRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data patient
+			err := ReadFromRequest(newJSONRequest(tt.body), &data)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, patient{Name: "Ana", Age: 30, Address: address{ZipCode: "68000"}}, data)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+			if tt.message != "" {
+				appErr, _ := apperror.As(err)
+				assert.Equal(t, tt.message, appErr.Error())
+			}
+		})
+	}
+
+	t.Run("unknown fields can be allowed", func(t *testing.T) {
+		var data patient
+		err := ReadFromRequest(newJSONRequest(`{"name": "Ana", "admin": true}`), &data, AllowUnknownFields())
+		assert.NoError(t, err)
+	})
+
+	t.Run("content type", func(t *testing.T) {
+		for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "application/json; charset=latin1"} {
+			r := newJSONRequest(`{}`)
+			r.Header.Set("Content-Type", contentType)
+			assert.ErrorIs(t, ReadFromRequest(r, &patient{}), ErrorUnsupportedMediaType, contentType)
+		}
+	})
+
+	t.Run("body size", func(t *testing.T) {
+		body := `{"name": "` + strings.Repeat("a", 100) + `"}`
+		err := ReadFromRequest(newJSONRequest(body), &patient{}, WithMaxBodySize(50))
+		assert.ErrorIs(t, err, ErrorBodyTooLarge)
+		assert.Equal(t, apperror.KindPayloadTooLarge, apperror.KindOf(err))
+
+		r := newJSONRequest(body)
+		r = r.WithContext(SetMaxBodySize(context.Background(), 50))
+		assert.ErrorIs(t, ReadFromRequest(r, &patient{}), ErrorBodyTooLarge, "the limit of the route")
+		assert.NoError(t, ReadFromRequest(newJSONRequest(body), &patient{}))
+	})
+}
+
+// FuzzReadFromRequest checks that any body is either decoded or rejected with
+// a client error, never a panic or an internal error.
+func FuzzReadFromRequest(f *testing.F) {
+	for _, seed := range []string{
+		`{"name": "Ana", "age": 30, "tags": ["a"], "address": {"zip_code": "68000"}}`,
+		`{"age": 1e400}`,
+		`{"age": -1, "name": null}`,
+		`{"address": []}`,
+		`{"name": "\ud800"}`,
+		`{}{}`,
+		`[]`,
+		`null`,
+		``,
+		"\xff\xfe",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, body []byte) {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		var data patient
+		err := ReadFromRequest(r, &data, WithMaxBodySize(1<<10))
+		if err != nil {
+			kind := apperror.KindOf(err)
+			if kind != apperror.KindValidation && kind != apperror.KindPayloadTooLarge {
+				t.Fatalf("unexpected error kind %s: %v", kind, err)
+			}
+			return
+		}
+		// a decoded body is a single valid json value
+		if !json.Valid(body) {
+			t.Fatalf("accepted invalid json %q", body)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package validator
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// curpPattern checks the structure of a CURP, the state is one of the
+	// 32 states or NE for people born abroad.
+	curpPattern = regexp.MustCompile(`^[A-Z][AEIOUX][A-Z]{2}\d{2}(0[1-9]|1[0-2])(0[1-9]|[12]\d|3[01])[HMX]` +
+		`(AS|BC|BS|CC|CL|CM|CS|CH|DF|DG|GT|GR|HG|JC|MC|MN|MS|NT|NL|OC|PL|QT|QR|SP|SL|SR|TC|TS|TL|VZ|YN|ZS|NE)` +
+		`[B-DF-HJ-NP-TV-Z]{3}[A-Z\d]\d$`)
+	// rfcPattern accepts the RFC of people, 4 letters, and companies, 3
+	// letters, with their homoclave.
+	rfcPattern = regexp.MustCompile(`^[A-ZÑ&]{3,4}\d{2}(0[1-9]|1[0-2])(0[1-9]|[12]\d|3[01])[A-Z\d]{2}[A\d]$`)
+	// voterKeyPattern is the clave de elector printed on the INE card.
+	voterKeyPattern = regexp.MustCompile(`^[A-Z]{6}\d{8}[HMX]\d{3}$`)
+	// phonePattern accepts a 10 digit mexican number or any E.164 number.
+	phonePattern   = regexp.MustCompile(`^(\+[1-9]\d{7,14}|[1-9]\d{9})$`)
+	phoneSeparator = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+)
+
+const mexicoCallingCode = "+52"
+
+// curpAlphabet gives the value of each character in the check digit.
+var curpAlphabet = []rune("0123456789ABCDEFGHIJKLMNÑOPQRSTUVWXYZ")
+
+func registerMexicanValidations(v *validator.Validate) {
+	v.RegisterValidation("curp", func(fl validator.FieldLevel) bool {
+		return IsCURP(fl.Field().String())
+	})
+	v.RegisterValidation("rfc", func(fl validator.FieldLevel) bool {
+		return IsRFC(fl.Field().String())
+	})
+	v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return IsPhone(fl.Field().String())
+	})
+	v.RegisterValidation("dni", func(fl validator.FieldLevel) bool {
+		return IsDNI(fl.Field().String())
+	})
+}
+
+func normalize(value string) string {
+	return strings.ToUpper(strings.TrimSpace(value))
+}
+
+// IsCURP checks the format and the check digit of a CURP.
+func IsCURP(value string) bool {
+	curp := normalize(value)
+	if !curpPattern.MatchString(curp) {
+		return false
+	}
+	sum := 0
+	for i, c := range []rune(curp)[:17] {
+		sum += slices.Index(curpAlphabet, c) * (18 - i)
+	}
+	digit := (10 - sum%10) % 10
+	return int(curp[17]-'0') == digit
+}
+
+func IsRFC(value string) bool {
+	return rfcPattern.MatchString(normalize(value))
+}
+
+func IsPhone(value string) bool {
+	return phonePattern.MatchString(phoneSeparator.Replace(strings.TrimSpace(value)))
+}
+
+// NormalizePhone returns a valid phone in E.164, the 10 digit numbers are
+// mexican ones.
+func NormalizePhone(value string) string {
+	phone := phoneSeparator.Replace(strings.TrimSpace(value))
+	if !strings.HasPrefix(phone, "+") {
+		phone = mexicoCallingCode + phone
+	}
+	return phone
+}
+
+// IsDNI accepts the identity documents we take from patients, a CURP or the
+// clave de elector of the INE card.
+func IsDNI(value string) bool {
+	return IsCURP(value) || voterKeyPattern.MatchString(normalize(value))
+}
@@ -0,0 +1,38 @@
+package validator
+
+import (
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+var customMessages = map[string]struct{ en, es string }{
+	"curp":   {"{0} must be a valid CURP", "{0} debe ser una CURP válida"},
+	"rfc":    {"{0} must be a valid RFC", "{0} debe ser un RFC válido"},
+	"phone":  {"{0} must be a valid phone number", "{0} debe ser un número de teléfono válido"},
+	"dni":    {"{0} must be a valid CURP or voter key", "{0} debe ser una CURP o clave de elector válida"},
+	"locale": {"{0} must be a supported language", "{0} debe ser un idioma soportado"},
+}
+
+// registerTranslations adds the messages of our custom tags, the default tags
+// are translated by the validator.
+func registerTranslations(v *validator.Validate, enTrans, esTrans ut.Translator) {
+	for tag, message := range customMessages {
+		registerTranslation(v, enTrans, tag, message.en)
+		registerTranslation(v, esTrans, tag, message.es)
+	}
+}
+
+func registerTranslation(v *validator.Validate, trans ut.Translator, tag, message string) {
+	err := v.RegisterTranslation(tag, trans, func(trans ut.Translator) error {
+		return trans.Add(tag, message, true)
+	}, func(trans ut.Translator, fe validator.FieldError) string {
+		translated, err := trans.T(tag, fe.Field())
+		if err != nil {
+			return fe.Error()
+		}
+		return translated
+	})
+	if err != nil {
+		panic(err)
+	}
+}
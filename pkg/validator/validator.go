@@ -1,15 +1,26 @@
 package validator
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"reflect"
 	"strings"
 
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	esTranslations "github.com/go-playground/validator/v10/translations/es"
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/i18n"
 )
 
+var ErrInvalidFields = apperror.Validation("invalid_fields", "the request has invalid fields")
+
 type Validator struct {
 	*validator.Validate
+	translator *ut.UniversalTranslator
 }
 
 var Val *validator.Validate
@@ -25,23 +36,61 @@ func New() *Validator {
 
 		return name
 	})
+	registerMexicanValidations(Val)
+	// the languages with a message catalog
+	Val.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		return i18n.Supported(fl.Field().String())
+	})
+
+	english := en.New()
+	translator := ut.New(english, english, es.New())
+	enTrans, _ := translator.GetTranslator(i18n.English)
+	esTrans, _ := translator.GetTranslator(i18n.Spanish)
+	// the default translations only fail on a malformed template
+	if err := enTranslations.RegisterDefaultTranslations(Val, enTrans); err != nil {
+		panic(err)
+	}
+	if err := esTranslations.RegisterDefaultTranslations(Val, esTrans); err != nil {
+		panic(err)
+	}
+	registerTranslations(Val, enTrans, esTrans)
+
 	return &Validator{
-		Validate: Val,
+		Validate:   Val,
+		translator: translator,
 	}
 
 }
 
-func (v *Validator) ValidateStruct(data any) error {
-	err := Val.Struct(data)
-	if err != nil {
-		if validationErr, ok := err.(validator.ValidationErrors); ok {
-			firstError := validationErr[0]
-			errName := firstError.Tag()
-			fieldName := firstError.Field()
+// ValidateStruct checks every field of data, the error lists all the invalid
+// fields with a message in the language of ctx.
+func (v *Validator) ValidateStruct(ctx context.Context, data any) error {
+	return v.translate(ctx, v.Struct(data))
+}
 
-			return fmt.Errorf("field '%s' is %s", fieldName, errName)
-		}
+// ValidatePartial is like ValidateStruct but only checks the given fields,
+// by their go name, so a rule of a dto can be applied to a single value.
+func (v *Validator) ValidatePartial(ctx context.Context, data any, fields ...string) error {
+	return v.translate(ctx, v.StructPartial(data, fields...))
+}
+
+func (v *Validator) translate(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var validationErr validator.ValidationErrors
+	if !errors.As(err, &validationErr) {
 		return err
 	}
-	return nil
+	trans, _ := v.translator.GetTranslator(i18n.FromContext(ctx))
+	fields := make([]apperror.FieldError, 0, len(validationErr))
+	for _, fieldErr := range validationErr {
+		fields = append(fields, apperror.FieldError{
+			Field:   fieldErr.Field(),
+			Tag:     fieldErr.Tag(),
+			Param:   fieldErr.Param(),
+			Message: fieldErr.Translate(trans),
+		})
+	}
+	return ErrInvalidFields.WithFields(fields)
 }
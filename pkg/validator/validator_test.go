@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oaxacos/vitacare/pkg/apperror"
+	"github.com/oaxacos/vitacare/pkg/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type profile struct {
+	Name  string `json:"name" validate:"required,min=3"`
+	Email string `json:"email" validate:"required,email"`
+	DNI   string `json:"dni" validate:"omitempty,dni"`
+	Phone string `json:"phone" validate:"omitempty,phone"`
+	Lang  string `json:"language" validate:"omitempty,locale"`
+}
+
+func TestValidateStruct(t *testing.T) {
+	v := New()
+	data := profile{Name: "Al", DNI: "XXXX", Phone: "12"}
+
+	err := v.ValidateStruct(context.Background(), data)
+	require.ErrorIs(t, err, ErrInvalidFields)
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	assert.Equal(t, []apperror.FieldError{
+		{Field: "name", Tag: "min", Param: "3", Message: "name must be at least 3 characters in length"},
+		{Field: "email", Tag: "required", Message: "email is a required field"},
+		{Field: "dni", Tag: "dni", Message: "dni must be a valid CURP or voter key"},
+		{Field: "phone", Tag: "phone", Message: "phone must be a valid phone number"},
+	}, appErr.Fields)
+
+	err = v.ValidateStruct(i18n.WithLocale(context.Background(), i18n.Spanish), data)
+	appErr, ok = apperror.As(err)
+	require.True(t, ok)
+	require.Len(t, appErr.Fields, 4)
+	assert.Equal(t, "email es un campo requerido", appErr.Fields[1].Message)
+	assert.Equal(t, "dni debe ser una CURP o clave de elector válida", appErr.Fields[2].Message)
+
+	valid := profile{Name: "Ana", Email: "ana@test.com", DNI: "looa531113htcpbn07", Phone: "+52 (951) 123-4567"}
+	assert.NoError(t, v.ValidateStruct(context.Background(), valid))
+}
+
+func TestMexicanValidations(t *testing.T) {
+	assert.True(t, IsCURP("LOOA531113HTCPBN07"))
+	assert.False(t, IsCURP("LOOA531113HTCPBN08"), "wrong check digit")
+	assert.False(t, IsCURP("LOOA531313HTCPBN07"), "invalid month")
+	assert.False(t, IsCURP("LOOA531113HXXPBN07"), "invalid state")
+
+	assert.True(t, IsRFC("LOOA531113FI5"))
+	assert.True(t, IsRFC("ABC990101AB1"), "companies have 3 letters")
+	assert.False(t, IsRFC("LOOA531313FI5"), "invalid month")
+
+	assert.True(t, IsDNI("GMVLMR80070501M100"), "clave de elector")
+	assert.True(t, IsDNI("LOOA531113HTCPBN07"))
+	assert.False(t, IsDNI("12345678"))
+
+	assert.True(t, IsPhone("9511234567"))
+	assert.True(t, IsPhone("+52 951 123 4567"))
+	assert.True(t, IsPhone("+1 (415) 555-2671"))
+	assert.False(t, IsPhone("0951123456"))
+	assert.False(t, IsPhone("123"))
+
+	assert.Equal(t, "+529511234567", NormalizePhone("951 123 4567"))
+	assert.Equal(t, "+529511234567", NormalizePhone(" +52 (951) 123-4567"))
+	assert.Equal(t, "+14155552671", NormalizePhone("+1 415.555.2671"))
+}
+
+func TestValidatePartial(t *testing.T) {
+	v := New()
+	data := profile{Name: "Al", DNI: "XXXX"}
+
+	err := v.ValidatePartial(context.Background(), data, "Name")
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	assert.Equal(t, []apperror.FieldError{
+		{Field: "name", Tag: "min", Param: "3", Message: "name must be at least 3 characters in length"},
+	}, appErr.Fields, "the other fields are not checked")
+	assert.NoError(t, v.ValidatePartial(context.Background(), profile{Name: "Ana"}, "Name"))
+}
+
+func TestLocale(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.ValidateStruct(context.Background(), profile{Name: "Ana", Email: "ana@test.com", Lang: "es"}))
+
+	err := v.ValidateStruct(context.Background(), profile{Name: "Ana", Email: "ana@test.com", Lang: "fr"})
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	require.Len(t, appErr.Fields, 1)
+	assert.Equal(t, "locale", appErr.Fields[0].Tag)
+	assert.Equal(t, "language must be a supported language", appErr.Fields[0].Message)
+}